@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"weavelab.xyz/goils/resources"
+)
+
+const usage = `goils is a resource (db table, model, endpoint) generation tool.
+
+Usage:
+  goils generate <generator> <name> [name:type[:null] ...] [flags]
+  goils generate migration all -in <definition> [flags]
+  goils generate migration <name> -in <definition> -diff <previous> [flags]
+  goils generate migration <add_column|drop_column|rename_column|change_type> <name> ... -in <definition> [flags]
+  goils new resource [flags]
+  goils schema > definition.schema.json
+
+Generators:
+  resource    runs every generator (or the ones given with -only)
+  migration   migration creating the table (goose unless -format says otherwise)
+  proto       proto messages for the crud options
+  sql         sqlc queries, schema and sqlc.yaml
+  tests       sqlmock tests for the sqlc queries
+
+Example:
+  goils generate resource sms id:uuid locationid:uuid text:string created_at:timestamptz:null \
+    --index idx_sms_loc:btree:locationid,id --crud show,index,create --owner schedule
+
+'goils generate migration all' writes the migrations of every resource of the
+definition, referenced tables first.
+'goils generate migration <name> -diff' writes the migration altering the table
+of the previous definition, SQL script or migrations directory into the current
+one, its Down reverting it.
+'goils generate migration add_column sms status:string' (or drop_column,
+rename_column and change_type) writes the migration of a single column change
+and updates the -in definition with it. The definition is written back as a
+single document, its comments and layout are not kept.
+Migrations are written for goose unless -format (or the migration_format of the
+definition) names golang-migrate, dbmate, flyway or atlas.
+SQL is generated for postgres unless -dialect (or the dialect of the definition)
+names mysql or sqlite. -diff and the column change migrations are only
+generated for postgres.
+'goils new resource' prompts for the table, its attributes, indexes, owner and
+crud options, previews the output and writes the definition file with it.
+'goils schema' prints the JSON Schema of definition files.
+
+Run 'goils generate -h' for the generate flags.
+`
+
+// CLI runs goils commands, reading from In and writing to Out
+type CLI struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+// New returns a CLI bound to stdin and stdout
+func New() CLI {
+	return CLI{
+		In:  os.Stdin,
+		Out: os.Stdout,
+	}
+}
+
+// Run executes the command given by args (without the program name)
+func (c CLI) Run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(c.Out, usage)
+		return nil
+	}
+
+	// the flags and definitions of a command set the package globals of
+	// resources, restored for the next command
+	database, migrations, templates := resources.Database, resources.Migrations, resources.TemplateDir
+	defer func() {
+		resources.ResetTypes()
+		resources.Database = database
+		resources.Migrations = migrations
+		resources.TemplateDir = templates
+	}()
+
+	switch args[0] {
+	case "generate", "g":
+		return c.generate(args[1:])
+	case "new":
+		return c.newCommand(args[1:])
+	case "schema":
+		return c.schema()
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(c.Out, usage)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q, run 'goils help' for usage", args[0])
+}
+
+// schema prints the JSON Schema of definition files
+func (c CLI) schema() error {
+	schema, err := resources.JSONSchema()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Out.Write(schema)
+
+	return err
+}
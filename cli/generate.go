@@ -0,0 +1,673 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"weavelab.xyz/goils/importer"
+	"weavelab.xyz/goils/resources"
+)
+
+// generators maps generator names to the resources generator they run
+var generators = map[string]func(resources.Resource) resources.GeneratedGroup{
+	"migration": resources.GenerateMigration,
+	"proto":     resources.GenerateProto,
+	"sql":       resources.GenerateSQL,
+	"tests":     resources.GenerateTests,
+}
+
+// generatorOrder is the order generators run in for "generate resource"
+var generatorOrder = []string{"migration", "proto", "sql", "tests"}
+
+type generateOptions struct {
+	in         string
+	sql        string
+	migrations string
+	diff       string
+	using      string
+	format     string
+	dialect    string
+	goStruct   string
+	proto      string
+	out        string
+	pkg        string
+	templates  string
+	types      string
+	owner      string
+	nullable   string
+	validate   bool
+	only       listFlag
+	crud       listFlag
+	indexes    repeatedFlag
+}
+
+func newGenerateFlags(opts *generateOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.StringVar(&opts.in, "in", "", "resource definition file (YAML or JSON)")
+	fs.StringVar(&opts.sql, "sql", "", "SQL script (i.e. a migration) to import the table from")
+	fs.StringVar(&opts.migrations, "migrations", "", "migrations directory to replay and import the table from")
+	fs.StringVar(&opts.diff, "diff", "", "previous definition, SQL script or migrations directory the migration alters the table from")
+	fs.StringVar(&opts.using, "using", "", "expression converting the values of a change_type migration (i.e. 'text::integer')")
+	fs.StringVar(&opts.format, "format", "", "migration format: "+strings.Join(resources.MigrationFormatNames(), ", ")+" (overrides the definition, goose by default)")
+	fs.StringVar(&opts.dialect, "dialect", "", "SQL dialect: "+strings.Join(resources.DialectNames(), ", ")+" (overrides the definition, postgres by default; -diff and column changes are postgres only)")
+	fs.StringVar(&opts.goStruct, "struct", "", "go source file with the struct to derive the resource from")
+	fs.StringVar(&opts.proto, "proto", "", "proto file with the message to derive the resource from")
+	fs.StringVar(&opts.out, "out", "output", "directory generated files are written to")
+	fs.StringVar(&opts.pkg, "package", "", "go package name used by generated code (overrides the definition)")
+	fs.StringVar(&opts.templates, "templates", "", "directory containing goils' templates folder")
+	fs.StringVar(&opts.types, "types", "", "definition file whose types are registered (i.e. shared types)")
+	fs.Var(&opts.only, "only", "comma separated generators to run for 'resource' (migration,proto,sql,tests)")
+	fs.Var(&opts.crud, "crud", "comma separated crud options (overrides the definition)")
+	fs.StringVar(&opts.owner, "owner", "", "owner of the table (overrides the definition)")
+	fs.Var(&opts.indexes, "index", "index as name:[type:]column,column (repeatable)")
+	fs.StringVar(&opts.nullable, "proto-nullable", "", "nullable proto fields as optional or wrapper (overrides the definition)")
+	fs.BoolVar(&opts.validate, "proto-validate", false, "adds protoc-gen-validate rules of the lengths and precisions to proto fields")
+
+	return fs
+}
+
+func (c CLI) generate(args []string) error {
+	opts := generateOptions{}
+	fs := newGenerateFlags(&opts)
+	fs.SetOutput(c.Out)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) < 2 {
+		return fmt.Errorf("usage: goils generate <resource|%s> <name> [name:type[:null] ...] [flags]", strings.Join(generatorOrder, "|"))
+	}
+
+	names, err := selectGenerators(positional[0], opts.only)
+	if err != nil {
+		return err
+	}
+
+	if err := setOutputFormats(opts); err != nil {
+		return err
+	}
+
+	if positional[1] == allResources {
+		return c.generateMigrations(opts, names)
+	}
+
+	if change, ok := columnChanges[positional[1]]; ok {
+		return c.generateColumnMigration(opts, names, change, positional[2:])
+	}
+
+	resource, err := resolveResource(opts, positional[1], positional[2:])
+	if err != nil {
+		return err
+	}
+
+	if opts.templates != "" {
+		resources.TemplateDir = opts.templates
+	}
+
+	if opts.diff != "" {
+		return c.generateAlterMigration(opts, names, resource)
+	}
+
+	return c.runGenerators(resource, names, opts.out)
+}
+
+// generateAlterMigration writes the migration altering the table read from
+// -diff into the one of resource
+func (c CLI) generateAlterMigration(opts generateOptions, names []string, resource resources.Resource) error {
+	if len(names) != 1 || names[0] != "migration" {
+		return fmt.Errorf("-diff can only be given to the migration generator")
+	}
+
+	if diagnostics := resource.Validate(); len(diagnostics) > 0 {
+		return invalidResource(resource, diagnostics)
+	}
+
+	previous, err := loadPrevious(opts.diff, resource.TableName)
+	if err != nil {
+		return err
+	}
+
+	return c.writeMigrations(resources.GeneratedGroups{resources.GenerateAlterMigration(previous, resource)}, opts.out)
+}
+
+// allResources names every resource of the -in definition, "all" being a
+// reserved word no table can be named after
+const allResources = "all"
+
+// generateMigrations writes the migrations of every resource of the
+// definition and of their join tables, ordered so referenced tables are
+// created first
+func (c CLI) generateMigrations(opts generateOptions, names []string) error {
+	if len(names) != 1 || names[0] != "migration" || opts.in == "" {
+		return fmt.Errorf("usage: goils generate migration all -in <definition> [flags]")
+	}
+
+	if opts.templates != "" {
+		resources.TemplateDir = opts.templates
+	}
+
+	if err := loadTypes(opts.types); err != nil {
+		return err
+	}
+
+	definition, err := resources.LoadDefinition(opts.in)
+	if err != nil {
+		return err
+	}
+
+	if err := definition.RegisterTypes(); err != nil {
+		return fmt.Errorf("%s: %v", opts.in, err)
+	}
+
+	for _, resource := range definition.Resources {
+		if diagnostics := append(resource.Validate(), resource.ValidateReferenced(definition.Resources)...); len(diagnostics) > 0 {
+			return invalidResource(resource, diagnostics)
+		}
+	}
+
+	joins, err := definition.JoinResources()
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.in, err)
+	}
+
+	group, err := resources.GenerateMigrations(append(definition.Resources, joins...))
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.in, err)
+	}
+
+	return c.writeMigrations(resources.GeneratedGroups{group}, opts.out)
+}
+
+// columnChange changes the columns of resource as given by args (the arguments
+// following the table name)
+type columnChange func(resource resources.Resource, args []string, opts generateOptions) (resources.ColumnMigration, error)
+
+// columnChanges are the targeted migrations of "generate migration", names no
+// table can be named after
+var columnChanges = map[string]columnChange{
+	"add_column":    addColumns,
+	"drop_column":   dropColumns,
+	"rename_column": renameColumn,
+	"change_type":   changeType,
+}
+
+func addColumns(resource resources.Resource, args []string, _ generateOptions) (resources.ColumnMigration, error) {
+	if len(args) == 0 {
+		return resources.ColumnMigration{}, fmt.Errorf("usage: goils generate migration add_column <table> name:type[:null] ... [flags]")
+	}
+
+	attrs := make([]resources.Attribute, len(args))
+	for i, arg := range args {
+		attr, err := resources.ParseAttribute(arg)
+		if err != nil {
+			return resources.ColumnMigration{}, err
+		}
+		attrs[i] = attr
+	}
+
+	return resources.AddColumns(resource, attrs...)
+}
+
+func dropColumns(resource resources.Resource, args []string, _ generateOptions) (resources.ColumnMigration, error) {
+	if len(args) == 0 {
+		return resources.ColumnMigration{}, fmt.Errorf("usage: goils generate migration drop_column <table> <column> ... [flags]")
+	}
+
+	return resources.DropColumns(resource, args...)
+}
+
+func renameColumn(resource resources.Resource, args []string, _ generateOptions) (resources.ColumnMigration, error) {
+	if len(args) != 2 {
+		return resources.ColumnMigration{}, fmt.Errorf("usage: goils generate migration rename_column <table> <column> <new name> [flags]")
+	}
+
+	return resources.RenameColumn(resource, args[0], args[1])
+}
+
+func changeType(resource resources.Resource, args []string, opts generateOptions) (resources.ColumnMigration, error) {
+	if len(args) != 1 {
+		return resources.ColumnMigration{}, fmt.Errorf("usage: goils generate migration change_type <table> <column>:<type> [-using <expression>] [flags]")
+	}
+
+	attr, err := resources.ParseAttribute(args[0])
+	if err != nil {
+		return resources.ColumnMigration{}, err
+	}
+
+	return resources.ChangeType(resource, attr.Name, attr.Type, opts.using)
+}
+
+// generateColumnMigration writes the migration of a targeted column change of
+// the table read from -in, -sql or -migrations and updates the -in definition
+// so the other generators follow the change
+func (c CLI) generateColumnMigration(opts generateOptions, names []string, change columnChange, args []string) error {
+	if len(names) != 1 || names[0] != "migration" {
+		return fmt.Errorf("column changes are only generated as migrations")
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: goils generate migration <add_column|drop_column|rename_column|change_type> <table> ... [flags]")
+	}
+
+	if opts.in == "" && opts.sql == "" && opts.migrations == "" {
+		return fmt.Errorf("the table %q is read from -in, -sql or -migrations", args[0])
+	}
+
+	if opts.templates != "" {
+		resources.TemplateDir = opts.templates
+	}
+
+	resource, err := resolveResource(opts, args[0], nil)
+	if err != nil {
+		return err
+	}
+
+	migration, err := change(resource, args[1:], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+
+	if err := c.writeMigrations(resources.GeneratedGroups{resources.GenerateColumnMigration(migration)}, opts.out); err != nil {
+		return err
+	}
+
+	if opts.in == "" {
+		return nil
+	}
+
+	return c.updateDefinition(opts.in, args[0], migration.Alter.Up.RenameColumns, func(resource resources.Resource) (resources.Resource, error) {
+		migration, err := change(resource, args[1:], opts)
+		return migration.Resource, err
+	})
+}
+
+// updateDefinition rewrites the definition file at path with the resource
+// named name changed by update and the foreign keys of the other resources
+// following its renamed columns, join tables are derived and left as they are
+func (c CLI) updateDefinition(path string, name string, renames []resources.Rename, update func(resources.Resource) (resources.Resource, error)) error {
+	definition, err := resources.LoadDefinition(path)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, resource := range definition.Resources {
+		if resource.TableName != name {
+			for _, rename := range renames {
+				definition.Resources[i] = resources.RenameReferenced(definition.Resources[i], name, rename.From, rename.To)
+			}
+			continue
+		}
+
+		found = true
+		if definition.Resources[i], err = update(resource); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	if err := resources.SaveDefinition(path, definition); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.Out, "update", path)
+
+	return nil
+}
+
+// setOutputFormats sets the format migrations are written in and the SQL
+// dialect from -format and -dialect, or the migration_format and dialect of
+// the -in definition. golang-migrate numbers the migrations after the ones
+// already in -out
+func setOutputFormats(opts generateOptions) error {
+	definition := resources.Definition{}
+	if opts.in != "" && (opts.format == "" || opts.dialect == "") {
+		var err error
+		if definition, err = resources.LoadDefinition(opts.in); err != nil {
+			return err
+		}
+	}
+
+	if opts.format != "" {
+		definition.MigrationFormat = opts.format
+	}
+	if opts.dialect != "" {
+		definition.Dialect = opts.dialect
+	}
+
+	dialect, err := resources.LookupDialect(definition.Dialect)
+	if err != nil {
+		return err
+	}
+	resources.Database = dialect
+
+	format, err := resources.LookupMigrationFormat(definition.MigrationFormat)
+	if err != nil {
+		return err
+	}
+
+	if migrate, ok := format.(resources.GolangMigrate); ok {
+		if migrate.Next, err = nextMigrationNumber(opts.out); err != nil {
+			return err
+		}
+		format = migrate
+	}
+
+	resources.Migrations = format
+
+	return nil
+}
+
+// nextMigrationNumber returns the number following the last golang-migrate
+// migration in dir (i.e. 3 after 000002_create_sms_table.up.sql), 1 when there
+// is none
+func nextMigrationNumber(dir string) (int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	last := 0
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".up.sql") {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.SplitN(file.Name(), "_", 2)[0])
+		if err == nil && number > last {
+			last = number
+		}
+	}
+
+	return last + 1, nil
+}
+
+// selectGenerators returns the generators to run for kind
+func selectGenerators(kind string, only []string) ([]string, error) {
+	if kind != "resource" {
+		if _, ok := generators[kind]; !ok {
+			return nil, fmt.Errorf("unknown generator %q", kind)
+		}
+
+		return []string{kind}, nil
+	}
+
+	if len(only) == 0 {
+		return generatorOrder, nil
+	}
+
+	for _, name := range only {
+		if _, ok := generators[name]; !ok {
+			return nil, fmt.Errorf("unknown generator %q given to -only", name)
+		}
+	}
+
+	return only, nil
+}
+
+// resolveResource builds the resource named name from its input (a file given
+// by flag or inline attributes) and applies the flag overrides
+func resolveResource(opts generateOptions, name string, attributes []string) (resources.Resource, error) {
+	sources := 0
+	for _, source := range []string{opts.in, opts.sql, opts.migrations, opts.goStruct, opts.proto} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return resources.Resource{}, fmt.Errorf("only one of -in, -sql, -migrations, -struct and -proto can be used")
+	}
+	if sources > 0 && len(attributes) > 0 {
+		return resources.Resource{}, fmt.Errorf("inline attributes cannot be combined with -in, -sql, -migrations, -struct or -proto")
+	}
+
+	if err := loadTypes(opts.types); err != nil {
+		return resources.Resource{}, err
+	}
+
+	resource, err := loadResource(opts.in, name)
+	if err != nil {
+		return resource, err
+	}
+
+	switch {
+	case opts.sql != "":
+		schema, err := importer.LoadSQL(opts.sql)
+		if err != nil {
+			return resource, err
+		}
+
+		if resource.CreateTable, err = importTable(schema, opts.sql, name); err != nil {
+			return resource, err
+		}
+	case opts.migrations != "":
+		schema, err := importer.ReplayMigrations(opts.migrations)
+		if err != nil {
+			return resource, err
+		}
+
+		if resource.CreateTable, err = importTable(schema, opts.migrations, name); err != nil {
+			return resource, err
+		}
+	case opts.goStruct != "":
+		if resource, err = importer.ParseStruct(opts.goStruct, name); err != nil {
+			return resource, err
+		}
+	case opts.proto != "":
+		if resource, err = importer.ParseProto(opts.proto, name); err != nil {
+			return resource, err
+		}
+	}
+
+	for _, attribute := range attributes {
+		attr, err := resources.ParseAttribute(attribute)
+		if err != nil {
+			return resource, err
+		}
+		resource.Attributes = append(resource.Attributes, attr)
+	}
+
+	for _, index := range opts.indexes {
+		idx, err := resources.ParseIndex(index)
+		if err != nil {
+			return resource, err
+		}
+		resource.Indexes = append(resource.Indexes, idx)
+	}
+
+	if opts.owner != "" {
+		resource.Owner = opts.owner
+	}
+
+	if opts.pkg != "" {
+		resource.Package = opts.pkg
+	}
+
+	if opts.nullable != "" {
+		resource.ProtoNullable = opts.nullable
+	}
+
+	if opts.validate {
+		resource.ProtoValidate = true
+	}
+
+	if len(opts.crud) > 0 {
+		resource.CrudOptions = make([]resources.CrudOption, len(opts.crud))
+		for i, option := range opts.crud {
+			resource.CrudOptions[i] = resources.CrudOption(option)
+		}
+	}
+
+	return resource, nil
+}
+
+// importTable returns the table named name from the schema read from source
+func importTable(schema *importer.Schema, source string, name string) (resources.CreateTable, error) {
+	table, ok := schema.Table(name)
+	if !ok {
+		return table, fmt.Errorf("%s: table %q is not created", source, name)
+	}
+
+	return table, nil
+}
+
+// loadResource reads the resource named name from the definition file at
+// path, when path is empty an attribute-less resource is returned
+func loadResource(path string, name string) (resources.Resource, error) {
+	if path == "" {
+		return resources.Resource{
+			CreateTable: resources.CreateTable{TableName: name},
+			Package:     "main",
+		}, nil
+	}
+
+	definition, err := resources.LoadDefinition(path)
+	if err != nil {
+		return resources.Resource{}, err
+	}
+
+	if err := definition.RegisterTypes(); err != nil {
+		return resources.Resource{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	resource, ok := definition.Find(name)
+	if !ok {
+		if resource, ok, err = definition.FindJoin(name); err != nil {
+			return resource, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if !ok {
+		return resource, fmt.Errorf("%s: %q is not defined (defines %s)", path, name, strings.Join(definition.Names(), ", "))
+	}
+
+	if resource.Package == "" {
+		resource.Package = "main"
+	}
+
+	return resource, nil
+}
+
+// loadPrevious reads the table named name from a definition file, a SQL script
+// or the replay of a migrations directory
+func loadPrevious(source string, name string) (resources.Resource, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return resources.Resource{}, err
+	}
+
+	var schema *importer.Schema
+	switch {
+	case info.IsDir():
+		schema, err = importer.ReplayMigrations(source)
+	case filepath.Ext(source) == ".sql":
+		schema, err = importer.LoadSQL(source)
+	default:
+		return loadResource(source, name)
+	}
+	if err != nil {
+		return resources.Resource{}, err
+	}
+
+	table, err := importTable(schema, source, name)
+
+	return resources.Resource{CreateTable: table}, err
+}
+
+// loadTypes registers the types of the definition file at path, if any
+func loadTypes(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	definition, err := resources.LoadDefinition(path)
+	if err != nil {
+		return err
+	}
+
+	if err := definition.RegisterTypes(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (c CLI) runGenerators(resource resources.Resource, names []string, out string) error {
+	if diagnostics := resource.Validate(); len(diagnostics) > 0 {
+		return invalidResource(resource, diagnostics)
+	}
+
+	groups := make(resources.GeneratedGroups, len(names))
+	for i, name := range names {
+		groups[i] = generators[name](resource)
+	}
+
+	if contains(names, "migration") {
+		return c.writeMigrations(groups, out)
+	}
+
+	return c.writeGroups(groups, out)
+}
+
+// writeMigrations writes the generated migrations into out, followed by the
+// atlas.sum of the directory when they are atlas migrations
+func (c CLI) writeMigrations(groups resources.GeneratedGroups, out string) error {
+	if err := c.writeGroups(groups, out); err != nil {
+		return err
+	}
+
+	if _, ok := resources.Migrations.(resources.Atlas); !ok {
+		return nil
+	}
+
+	sum, err := resources.AtlasSum(out)
+	if err != nil {
+		return err
+	}
+
+	return c.writeGroups(resources.GeneratedGroups{{sum}}, out)
+}
+
+// writeGroups writes the generated files into out, none when any failed
+func (c CLI) writeGroups(groups resources.GeneratedGroups, out string) error {
+	for _, group := range groups {
+		for _, result := range group {
+			if result.HasError() {
+				return fmt.Errorf("generating %s: %v", result.FileOut, result.Error)
+			}
+		}
+	}
+
+	for _, group := range groups {
+		if err := group.CreateFilesIn(out); err != nil {
+			return err
+		}
+
+		for _, result := range group {
+			fmt.Fprintln(c.Out, "create", filepath.Join(out, result.FileOut))
+		}
+	}
+
+	return nil
+}
+
+// invalidResource lists the diagnostics of resource, one per line
+func invalidResource(resource resources.Resource, diagnostics resources.Diagnostics) error {
+	lines := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		lines[i] = "  " + diagnostic.Error()
+	}
+
+	return fmt.Errorf("invalid resource %q:\n%s", resource.TableName, strings.Join(lines, "\n"))
+}
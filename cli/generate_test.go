@@ -0,0 +1,520 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func Test_parseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		wantOut  string
+		wantOnly []string
+	}{
+		{
+			name:    "flags after positional arguments",
+			args:    []string{"resource", "sms", "-out", "dir"},
+			want:    []string{"resource", "sms"},
+			wantOut: "dir",
+		},
+		{
+			name:     "flags mixed with positional arguments",
+			args:     []string{"resource", "-only", "sql", "sms", "--only=proto"},
+			want:     []string{"resource", "sms"},
+			wantOut:  "output",
+			wantOnly: []string{"sql", "proto"},
+		},
+		{
+			name:    "everything after -- is positional",
+			args:    []string{"resource", "--", "sms", "-out"},
+			want:    []string{"resource", "sms", "-out"},
+			wantOut: "output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("parseFlags", t, func() {
+				opts := generateOptions{}
+				fs := newGenerateFlags(&opts)
+
+				got, err := parseFlags(fs, tt.args)
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+				So(opts.out, ShouldEqual, tt.wantOut)
+				So([]string(opts.only), ShouldResemble, tt.wantOnly)
+			})
+		})
+	}
+}
+
+func Test_selectGenerators(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		only    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "resource runs every generator",
+			kind: "resource",
+			want: []string{"migration", "proto", "sql", "tests"},
+		},
+		{
+			name: "resource runs the generators given with only",
+			kind: "resource",
+			only: []string{"proto", "sql"},
+			want: []string{"proto", "sql"},
+		},
+		{
+			name: "a single generator",
+			kind: "migration",
+			want: []string{"migration"},
+		},
+		{
+			name:    "unknown generator",
+			kind:    "model",
+			wantErr: true,
+		},
+		{
+			name:    "unknown generator given to only",
+			kind:    "resource",
+			only:    []string{"model"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("selectGenerators", t, func() {
+				got, err := selectGenerators(tt.kind, tt.only)
+				if tt.wantErr {
+					So(err, ShouldNotBeNil)
+					return
+				}
+
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		})
+	}
+}
+
+func Test_resolveResource(t *testing.T) {
+	Convey("resolveResource builds the resource from inline attributes", t, func() {
+		opts := generateOptions{}
+		fs := newGenerateFlags(&opts)
+
+		positional, err := parseFlags(fs, []string{
+			"resource", "setting", "id:uuid", "locationid:uuid",
+			"--index", "idx_appt_type_location_id:btree:locationid,id",
+			"--crud", "show,index,create", "--owner", "schedule",
+		})
+		So(err, ShouldBeNil)
+
+		got, err := resolveResource(opts, positional[1], positional[2:])
+		So(err, ShouldBeNil)
+		So(got, ShouldResemble, resources.Resource{
+			CreateTable: resources.CreateTable{
+				TableName: "setting",
+				Attributes: resources.Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "locationid", Type: "UUID"},
+				},
+				Indexes: resources.Indexes{
+					{
+						Name:    "idx_appt_type_location_id",
+						Type:    "BTREE",
+						Columns: []string{"locationid", "id"},
+					},
+				},
+				Owner: "schedule",
+			},
+			CrudOptions: []resources.CrudOption{"show", "index", "create"},
+			Package:     "main",
+		})
+	})
+
+	Convey("resolveResource rejects inline attributes with a definition file", t, func() {
+		_, err := resolveResource(generateOptions{in: "testdata/sms.json"}, "sms", []string{"id:uuid"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("resolveResource reports invalid inline attributes", t, func() {
+		_, err := resolveResource(generateOptions{}, "sms", []string{"id"})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestCLI_generate(t *testing.T) {
+	Convey("generate resource writes the generated files", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		out := &bytes.Buffer{}
+		c := CLI{Out: out}
+
+		err = c.Run([]string{"generate", "resource", "sms", "-in", "testdata/sms.json", "-out", dir, "-only", "proto,sql"})
+		So(err, ShouldBeNil)
+
+		for _, file := range []string{"proto.proto", "queries.sql", "sqlc.yaml", "schema.sql"} {
+			_, err := os.Stat(filepath.Join(dir, file))
+			So(err, ShouldBeNil)
+			So(out.String(), ShouldContainSubstring, filepath.Join(dir, file))
+		}
+
+		schema, err := ioutil.ReadFile(filepath.Join(dir, "schema.sql"))
+		So(err, ShouldBeNil)
+		So(string(schema), ShouldContainSubstring, "locationid UUID NOT NULL")
+	})
+
+	Convey("generate imports the table from a SQL script", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "sql", "sms", "-sql", "testdata/create_sms.sql", "-crud", "show,create", "-out", dir})
+		So(err, ShouldBeNil)
+
+		queries, err := ioutil.ReadFile(filepath.Join(dir, "queries.sql"))
+		So(err, ShouldBeNil)
+		So(string(queries), ShouldContainSubstring, "-- name: GetSms :one")
+		So(string(queries), ShouldContainSubstring, "-- name: CreateSms :one")
+
+		schema, err := ioutil.ReadFile(filepath.Join(dir, "schema.sql"))
+		So(err, ShouldBeNil)
+		So(string(schema), ShouldContainSubstring, "    text varchar(120),\n    PRIMARY KEY (id)\n")
+	})
+
+	Convey("generate fails when the definition is for another table", t, func() {
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err := c.Run([]string{"generate", "sql", "setting", "-in", "testdata/sms.json"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate uses the types registered with -types", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "sql", "invoice", "id:uuid", "total:money", "-types", "testdata/types.yaml", "-out", dir})
+		So(err, ShouldBeNil)
+
+		schema, err := ioutil.ReadFile(filepath.Join(dir, "schema.sql"))
+		So(err, ShouldBeNil)
+		So(string(schema), ShouldContainSubstring, "total numeric(12,2) NOT NULL")
+
+		err = c.Run([]string{"generate", "sql", "invoice", "id:uuid", "total:money", "-out", dir})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate wraps nullable proto fields given -proto-nullable", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "proto", "sms", "id:uuid", "text:text:null", "-crud", "create", "-proto-nullable", "wrapper", "-out", dir})
+		So(err, ShouldBeNil)
+
+		proto, err := ioutil.ReadFile(filepath.Join(dir, "proto.proto"))
+		So(err, ShouldBeNil)
+		So(string(proto), ShouldContainSubstring, "import \"google/protobuf/wrappers.proto\";\n")
+		So(string(proto), ShouldContainSubstring, "  google.protobuf.StringValue Text = 1;\n")
+	})
+
+	Convey("generate adds protoc-gen-validate rules given -proto-validate", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "proto", "sms", "id:uuid", "text:string(160)", "-crud", "create", "-proto-validate", "-out", dir})
+		So(err, ShouldBeNil)
+
+		proto, err := ioutil.ReadFile(filepath.Join(dir, "proto.proto"))
+		So(err, ShouldBeNil)
+		So(string(proto), ShouldContainSubstring, "  string Text = 1 [(validate.rules).string.max_len = 160]; // max length 160\n")
+	})
+
+	Convey("generate migration all creates referenced tables first", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		resources.MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		defer func() { resources.MockedTime = time.Time{} }()
+
+		out := &bytes.Buffer{}
+		c := CLI{Out: out}
+
+		err = c.Run([]string{"generate", "migration", "all", "-in", "testdata/locations.yaml", "-out", dir})
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldEqual, "create "+filepath.Join(dir, "20200615120000_create_location_table.sql")+"\n"+
+			"create "+filepath.Join(dir, "20200615120001_create_setting_table.sql")+"\n"+
+			"create "+filepath.Join(dir, "20200615120002_create_tag_table.sql")+"\n"+
+			"create "+filepath.Join(dir, "20200615120003_create_location_tag_table.sql")+"\n")
+
+		for _, table := range []string{"location", "tag"} {
+			migration, err := ioutil.ReadFile(filepath.Join(dir, "20200615120000_create_location_table.sql"))
+			if table == "tag" {
+				migration, err = ioutil.ReadFile(filepath.Join(dir, "20200615120002_create_tag_table.sql"))
+			}
+			So(err, ShouldBeNil)
+			So(string(migration), ShouldContainSubstring, "\tPRIMARY KEY (id)\n")
+		}
+
+		err = c.Run([]string{"generate", "sql", "all", "-in", "testdata/locations.yaml", "-out", dir})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate migration -diff alters the previous table", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		resources.MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		defer func() { resources.MockedTime = time.Time{} }()
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "migration", "sms", "id:uuid", "locationid:uuid", "text:text", "-diff", "testdata/create_sms.sql", "-out", dir})
+		So(err, ShouldBeNil)
+
+		migration, err := ioutil.ReadFile(filepath.Join(dir, "20200615120000_alter_sms_table.sql"))
+		So(err, ShouldBeNil)
+		So(string(migration), ShouldContainSubstring, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms ALTER COLUMN text TYPE text;\n"+
+			"ALTER TABLE sms ALTER COLUMN text SET NOT NULL;\n")
+		So(string(migration), ShouldContainSubstring, "-- +goose Down\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms ALTER COLUMN text TYPE varchar(120);\n"+
+			"ALTER TABLE sms ALTER COLUMN text DROP NOT NULL;\n")
+
+		err = c.Run([]string{"generate", "sql", "sms", "id:uuid", "-diff", "testdata/create_sms.sql", "-out", dir})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate migration add_column updates the definition", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		resources.MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		defer func() { resources.MockedTime = time.Time{} }()
+
+		definition := filepath.Join(dir, "sms.yaml")
+		err = ioutil.WriteFile(definition, []byte("table_name: sms\nattributes:\n  - name: id\n    type: UUID\n"), 0644)
+		So(err, ShouldBeNil)
+
+		out := &bytes.Buffer{}
+		c := CLI{Out: out}
+
+		err = c.Run([]string{"generate", "migration", "add_column", "sms", "status:string", "-in", definition, "-out", dir})
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldEqual, "create "+filepath.Join(dir, "20200615120000_add_status_to_sms.sql")+"\n"+
+			"update "+definition+"\n")
+
+		migration, err := ioutil.ReadFile(filepath.Join(dir, "20200615120000_add_status_to_sms.sql"))
+		So(err, ShouldBeNil)
+		So(string(migration), ShouldContainSubstring, "ALTER TABLE sms ADD COLUMN status varchar(120) NOT NULL;\n")
+
+		updated, err := resources.LoadDefinition(definition)
+		So(err, ShouldBeNil)
+		So(updated.Resources[0].Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "status", Type: "string"},
+		})
+
+		err = c.Run([]string{"generate", "migration", "change_type", "sms", "status:text", "-using", "status::text", "-in", definition, "-out", dir})
+		So(err, ShouldBeNil)
+
+		err = c.Run([]string{"generate", "migration", "drop_column", "sms", "missing", "-in", definition, "-out", dir})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `sms: column "missing" of "sms" does not exist`)
+
+		err = c.Run([]string{"generate", "migration", "rename_column", "sms", "status"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate migration rename_column updates the foreign keys of the other resources", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		definition := filepath.Join(dir, "sms.yaml")
+		err = ioutil.WriteFile(definition, []byte("table_name: sms\nattributes:\n  - name: id\n    type: UUID\n"+
+			"---\ntable_name: reply\nattributes:\n  - name: sms_id\n    type: UUID\n    references:\n      table: sms\n"), 0644)
+		So(err, ShouldBeNil)
+
+		c := CLI{Out: &bytes.Buffer{}}
+		err = c.Run([]string{"generate", "migration", "rename_column", "sms", "id", "sms_id", "-in", definition, "-out", dir})
+		So(err, ShouldBeNil)
+
+		updated, err := resources.LoadDefinition(definition)
+		So(err, ShouldBeNil)
+		So(updated.Resources[0].Attributes[0].Name, ShouldEqual, "sms_id")
+		So(updated.Resources[1].Attributes[0].References, ShouldResemble, &resources.Reference{Table: "sms", Column: "sms_id"})
+	})
+
+	Convey("generate migration -format numbers golang-migrate migrations after the existing ones", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, "000002_create_location_table.up.sql"), []byte("CREATE TABLE location (id uuid);\n"), 0644)
+		So(err, ShouldBeNil)
+
+		out := &bytes.Buffer{}
+		c := CLI{Out: out}
+
+		err = c.Run([]string{"generate", "migration", "sms", "id:uuid", "-format", "golang-migrate", "-out", dir})
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldEqual, "create "+filepath.Join(dir, "000003_create_sms_table.up.sql")+"\n"+
+			"create "+filepath.Join(dir, "000003_create_sms_table.down.sql")+"\n")
+
+		So(resources.Migrations, ShouldResemble, resources.Goose{})
+
+		down, err := ioutil.ReadFile(filepath.Join(dir, "000003_create_sms_table.down.sql"))
+		So(err, ShouldBeNil)
+		So(string(down), ShouldEndWith, "DROP TABLE sms;\n")
+
+		err = c.Run([]string{"generate", "migration", "sms", "id:uuid", "-format", "liquibase", "-out", dir})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `unknown migration format "liquibase" (expected one of atlas, dbmate, flyway, golang-migrate, goose)`)
+	})
+
+	Convey("generate -dialect writes mysql", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "resource", "sms", "id:uuid", "text:text", "-crud", "show", "-only", "sql", "-dialect", "mysql", "-out", dir})
+		So(err, ShouldBeNil)
+		So(resources.Database, ShouldResemble, resources.Postgres{})
+
+		queries, err := ioutil.ReadFile(filepath.Join(dir, "queries.sql"))
+		So(err, ShouldBeNil)
+		So(string(queries), ShouldContainSubstring, "WHERE id = ? LIMIT 1;")
+
+		schema, err := ioutil.ReadFile(filepath.Join(dir, "schema.sql"))
+		So(err, ShouldBeNil)
+		So(string(schema), ShouldContainSubstring, "id CHAR(36) NOT NULL,\n    text TEXT NOT NULL,\n    PRIMARY KEY (id)\n")
+
+		err = c.Run([]string{"generate", "sql", "sms", "id:uuid", "-dialect", "oracle", "-out", dir})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("generate migration -format atlas writes the atlas.sum of the directory", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		out := &bytes.Buffer{}
+		c := CLI{Out: out}
+
+		err = c.Run([]string{"generate", "migration", "sms", "id:uuid", "-format", "atlas", "-out", dir})
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldEndWith, "create "+filepath.Join(dir, "atlas.sum")+"\n")
+
+		sum, err := ioutil.ReadFile(filepath.Join(dir, "atlas.sum"))
+		So(err, ShouldBeNil)
+		want, err := resources.AtlasSum(dir)
+		So(err, ShouldBeNil)
+		So(string(sum), ShouldEqual, want.Output)
+		So(string(sum), ShouldContainSubstring, "_create_sms_table.sql h1:")
+	})
+
+	Convey("generate restores the dialect, format and templates of the next command", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "migration", "sms", "id:uuid", "-dialect", "mysql", "-format", "atlas", "-templates", dir, "-out", dir})
+		So(err, ShouldBeNil)
+		So(resources.Database, ShouldResemble, resources.Postgres{})
+		So(resources.Migrations, ShouldResemble, resources.Goose{})
+		So(resources.TemplateDir, ShouldBeEmpty)
+	})
+
+	Convey("generate -dialect writes sqlite and its in-process tests", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "resource", "sms", "id:uuid", "text:text", "-crud", "show", "-only", "sql,tests", "-dialect", "sqlite", "-out", dir})
+		So(err, ShouldBeNil)
+		So(resources.Database, ShouldResemble, resources.Postgres{})
+
+		schema, err := ioutil.ReadFile(filepath.Join(dir, "schema.sql"))
+		So(err, ShouldBeNil)
+		So(string(schema), ShouldContainSubstring, "id TEXT NOT NULL,\n    text TEXT NOT NULL,\n    PRIMARY KEY (id)\n")
+
+		tests, err := ioutil.ReadFile(filepath.Join(dir, "queries_sqlite_test.go"))
+		So(err, ShouldBeNil)
+		So(string(tests), ShouldContainSubstring, "func TestSmsSQLite(t *testing.T) {")
+	})
+
+	Convey("generate finds the join table of a has_many_through", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err = c.Run([]string{"generate", "sql", "location_tag", "-in", "testdata/locations.yaml", "-out", dir})
+		So(err, ShouldBeNil)
+
+		queries, err := ioutil.ReadFile(filepath.Join(dir, "queries.sql"))
+		So(err, ShouldBeNil)
+		So(string(queries), ShouldContainSubstring, "-- name: AttachTagToLocation :exec\n")
+	})
+
+	Convey("generate lists every problem of an invalid resource", t, func() {
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err := c.Run([]string{"generate", "sql", "sms", "id:uuid", "user:strin", "--index", "idx_sms:location_id"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `invalid resource "sms":
+  attributes[1].name: "user" is a reserved SQL word
+  attributes[1].type: unknown attribute type "strin"
+  indexes[0].columns[0]: "location_id" is not an attribute`)
+	})
+
+	Convey("generate requires a generator and a name", t, func() {
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err := c.Run([]string{"generate", "resource"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("flag errors are returned", t, func() {
+		c := CLI{Out: &bytes.Buffer{}}
+
+		err := c.Run([]string{"generate", "resource", "sms", "-unknown"})
+		So(err, ShouldNotBeNil)
+		So(err, ShouldNotEqual, flag.ErrHelp)
+	})
+}
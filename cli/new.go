@@ -0,0 +1,391 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"weavelab.xyz/goils/resources"
+)
+
+// previewGenerators are shown by the wizard before anything is written
+var previewGenerators = []string{"migration", "proto", "sql"}
+
+type newOptions struct {
+	out        string
+	definition string
+	templates  string
+	types      string
+}
+
+func (c CLI) newCommand(args []string) error {
+	opts := newOptions{}
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(c.Out)
+	fs.StringVar(&opts.out, "out", "output", "directory generated files are written to")
+	fs.StringVar(&opts.definition, "definition", "", "definition file to write (defaults to <table_name>.yaml)")
+	fs.StringVar(&opts.templates, "templates", "", "directory containing goils' templates folder")
+	fs.StringVar(&opts.types, "types", "", "definition file whose types are registered (i.e. shared types)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 || positional[0] != "resource" {
+		return fmt.Errorf("usage: goils new resource [flags]")
+	}
+
+	if opts.templates != "" {
+		resources.TemplateDir = opts.templates
+	}
+
+	if err := loadTypes(opts.types); err != nil {
+		return err
+	}
+
+	w := wizard{
+		in:  bufio.NewScanner(c.In),
+		out: c.Out,
+	}
+
+	resource, err := w.resource()
+	if err != nil {
+		return err
+	}
+
+	if diagnostics := resource.Validate(); len(diagnostics) > 0 {
+		return invalidResource(resource, diagnostics)
+	}
+
+	for _, name := range previewGenerators {
+		for _, result := range generators[name](resource) {
+			if result.HasError() {
+				return fmt.Errorf("generating %s: %v", result.FileOut, result.Error)
+			}
+
+			fmt.Fprintf(c.Out, "\n==> %s\n%s\n", result.FileOut, result.Output)
+		}
+	}
+
+	if opts.definition == "" {
+		opts.definition = resource.TableName + ".yaml"
+	}
+
+	write, err := w.confirm(fmt.Sprintf("Write %s and the generated files to %s?", opts.definition, opts.out), true)
+	if err != nil || !write {
+		return err
+	}
+
+	definition := resources.Definition{Resources: []resources.Resource{resource}}
+	if err := resources.SaveDefinition(opts.definition, definition); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.Out, "create", opts.definition)
+
+	return c.runGenerators(resource, generatorOrder, opts.out)
+}
+
+// wizard prompts for the parts of a resource
+type wizard struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+var errInputEnded = errors.New("input ended before the resource was complete")
+
+// ask prompts with question and returns the trimmed answer, or def when it is empty
+func (w wizard) ask(question string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	if !w.in.Scan() {
+		if err := w.in.Err(); err != nil {
+			return "", err
+		}
+
+		return "", errInputEnded
+	}
+
+	answer := strings.TrimSpace(w.in.Text())
+	if answer == "" {
+		return def, nil
+	}
+
+	return answer, nil
+}
+
+// askUntil asks question until valid accepts the answer
+func (w wizard) askUntil(question string, def string, valid func(string) error) (string, error) {
+	for {
+		answer, err := w.ask(question, def)
+		if err != nil {
+			return "", err
+		}
+
+		if err := valid(answer); err != nil {
+			fmt.Fprintln(w.out, " ", err)
+			continue
+		}
+
+		return answer, nil
+	}
+}
+
+func (w wizard) confirm(question string, def bool) (bool, error) {
+	options := "y/N"
+	if def {
+		options = "Y/n"
+	}
+
+	answer, err := w.askUntil(fmt.Sprintf("%s (%s)", question, options), "", func(a string) error {
+		switch strings.ToLower(a) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+
+		return fmt.Errorf("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+
+	return def, nil
+}
+
+func required(a string) error {
+	if a == "" {
+		return fmt.Errorf("a value is required")
+	}
+
+	return nil
+}
+
+func (w wizard) resource() (resources.Resource, error) {
+	resource := resources.Resource{}
+
+	name, err := w.askUntil("Table name", "", required)
+	if err != nil {
+		return resource, err
+	}
+	resource.TableName = name
+
+	if resource.Package, err = w.ask("Package", "main"); err != nil {
+		return resource, err
+	}
+
+	if resource.Attributes, err = w.attributes(resource.TableName); err != nil {
+		return resource, err
+	}
+
+	if resource.Indexes, err = w.indexes(resource.Attributes); err != nil {
+		return resource, err
+	}
+
+	if resource.Owner, err = w.ask("Owner (empty for none)", ""); err != nil {
+		return resource, err
+	}
+
+	if resource.CrudOptions, err = w.crudOptions(); err != nil {
+		return resource, err
+	}
+
+	return resource, nil
+}
+
+func (w wizard) attributes(table string) (resources.Attributes, error) {
+	fmt.Fprintln(w.out, "\nSupported types:")
+	for i, typ := range resources.AttributeTypes {
+		fmt.Fprintf(w.out, "  %2d) %s\n", i+1, typ)
+	}
+	fmt.Fprintln(w.out, "  or enum for a list of values")
+
+	attributes := make(resources.Attributes, 0)
+	for {
+		name, err := w.askUntil("Attribute name (empty to finish)", "", func(a string) error {
+			if a == "" && len(attributes) == 0 {
+				return fmt.Errorf("at least one attribute is required")
+			}
+			if attributes.Any(func(attr resources.Attribute) bool { return attr.Name == a }) {
+				return fmt.Errorf("attribute %q already exists", a)
+			}
+
+			return nil
+		})
+		if err != nil || name == "" {
+			return attributes, err
+		}
+
+		typ, err := w.askUntil("  Type (name or number, i.e. varchar(40) or integer[])", "", func(a string) error {
+			if a == "enum" {
+				return nil
+			}
+
+			_, err := attributeType(a)
+			return err
+		})
+		if err != nil {
+			return attributes, err
+		}
+
+		attr := resources.Attribute{Name: name}
+		if typ == "enum" {
+			if attr, err = w.enum(table, name); err != nil {
+				return attributes, err
+			}
+		} else {
+			attr.Type, _ = attributeType(typ)
+		}
+
+		nullable, err := w.confirm("  Nullable?", false)
+		if err != nil {
+			return attributes, err
+		}
+
+		attr.Nullable = nullable
+		attributes = append(attributes, attr)
+	}
+}
+
+// enum asks for the type name and values of an enum attribute
+func (w wizard) enum(table string, name string) (resources.Attribute, error) {
+	attr := resources.Attribute{Name: name}
+
+	typ, err := w.ask("  Enum type name", table+"_"+name)
+	if err != nil {
+		return attr, err
+	}
+	attr.Type = resources.AttributeType(typ)
+
+	values, err := w.askUntil("  Values (comma separated)", "", func(a string) error {
+		if len(splitList(a)) == 0 {
+			return fmt.Errorf("at least one value is required")
+		}
+
+		return nil
+	})
+	attr.Values = splitList(values)
+
+	return attr, err
+}
+
+// attributeType resolves a wizard answer (a type name or its number in the list)
+func attributeType(answer string) (resources.AttributeType, error) {
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(resources.AttributeTypes) {
+			return "", fmt.Errorf("choose a number between 1 and %d", len(resources.AttributeTypes))
+		}
+
+		return resources.AttributeTypes[n-1], nil
+	}
+
+	if typ := resources.NormalizeType(answer); typ.IsValid() {
+		return typ, nil
+	}
+
+	return "", fmt.Errorf("unsupported type %q", answer)
+}
+
+func (w wizard) indexes(attributes resources.Attributes) (resources.Indexes, error) {
+	indexes := make(resources.Indexes, 0)
+	for {
+		name, err := w.ask("Index name (empty to finish)", "")
+		if err != nil || name == "" {
+			return indexes, err
+		}
+
+		typ, err := w.ask("  Index type", "BTREE")
+		if err != nil {
+			return indexes, err
+		}
+
+		columns, err := w.askUntil("  Columns (comma separated)", "", func(a string) error {
+			if a == "" {
+				return fmt.Errorf("at least one column is required")
+			}
+
+			for _, column := range splitList(a) {
+				if !attributes.Any(func(attr resources.Attribute) bool { return attr.Name == column }) {
+					return fmt.Errorf("%q is not an attribute", column)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return indexes, err
+		}
+
+		indexes = append(indexes, resources.Index{
+			Name:    name,
+			Type:    strings.ToUpper(typ),
+			Columns: splitList(columns),
+		})
+	}
+}
+
+func (w wizard) crudOptions() ([]resources.CrudOption, error) {
+	supported := make([]string, len(resources.CrudOptions))
+	for i, option := range resources.CrudOptions {
+		supported[i] = string(option)
+	}
+
+	answer, err := w.askUntil(
+		fmt.Sprintf("CRUD options (comma separated from %s, 'none' for none)", strings.Join(supported, ", ")),
+		strings.Join(supported, ","),
+		func(a string) error {
+			if a == "none" {
+				return nil
+			}
+
+			for _, option := range splitList(a) {
+				if !contains(supported, option) {
+					return fmt.Errorf("unsupported crud option %q", option)
+				}
+			}
+
+			return nil
+		},
+	)
+	if err != nil || answer == "none" {
+		return nil, err
+	}
+
+	options := make([]resources.CrudOption, 0)
+	for _, option := range splitList(answer) {
+		options = append(options, resources.CrudOption(option))
+	}
+
+	return options, nil
+}
+
+func splitList(s string) []string {
+	l := listFlag{}
+	l.Set(s)
+
+	return l
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
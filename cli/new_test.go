@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func TestCLI_new(t *testing.T) {
+	Convey("new resource writes the definition and the generated files", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		answers := []string{
+			"sms",         // table name
+			"",            // package
+			"id", "1", "", // id UUID
+			"text", "bogus", "string", "y", // unsupported type is asked again
+			"",                                    // done with attributes
+			"idx_sms_text", "", "missing", "text", // unknown column is asked again
+			"",            // done with indexes
+			"schedule",    // owner
+			"show,create", // crud options
+			"",            // confirm
+		}
+
+		out := &bytes.Buffer{}
+		c := CLI{
+			In:  strings.NewReader(strings.Join(answers, "\n") + "\n"),
+			Out: out,
+		}
+
+		definitionFile := filepath.Join(dir, "sms.yaml")
+		err = c.Run([]string{"new", "resource", "-out", dir, "-definition", definitionFile})
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldContainSubstring, `unsupported type "bogus"`)
+		So(out.String(), ShouldContainSubstring, `"missing" is not an attribute`)
+		So(out.String(), ShouldContainSubstring, "_create_sms_table.sql")
+
+		definition, err := resources.LoadDefinition(definitionFile)
+		So(err, ShouldBeNil)
+		So(definition.Resources, ShouldResemble, []resources.Resource{{
+			CreateTable: resources.CreateTable{
+				TableName: "sms",
+				Attributes: resources.Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "text", Type: "string", Nullable: true},
+				},
+				Indexes: resources.Indexes{
+					{Name: "idx_sms_text", Type: "BTREE", Columns: []string{"text"}},
+				},
+				Owner: "schedule",
+			},
+			CrudOptions: []resources.CrudOption{"show", "create"},
+			Package:     "main",
+		}})
+
+		for _, file := range []string{"proto.proto", "queries.sql", "schema.sql"} {
+			_, err := os.Stat(filepath.Join(dir, file))
+			So(err, ShouldBeNil)
+		}
+	})
+
+	Convey("new resource writes nothing when the preview is declined", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := CLI{
+			In:  strings.NewReader("sms\n\nid\nUUID\n\n\n\n\n\nn\n"),
+			Out: &bytes.Buffer{},
+		}
+
+		err = c.Run([]string{"new", "resource", "-out", filepath.Join(dir, "output"), "-definition", filepath.Join(dir, "sms.yaml")})
+		So(err, ShouldBeNil)
+
+		files, err := ioutil.ReadDir(dir)
+		So(err, ShouldBeNil)
+		So(files, ShouldBeEmpty)
+	})
+
+	Convey("new resource fails when the input ends early", t, func() {
+		c := CLI{
+			In:  strings.NewReader("sms\n"),
+			Out: &bytes.Buffer{},
+		}
+
+		err := c.Run([]string{"new", "resource"})
+		So(err, ShouldEqual, errInputEnded)
+	})
+}
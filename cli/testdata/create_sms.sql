@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS sms
+(
+	id UUID NOT NULL,
+	locationid UUID NOT NULL,
+	text varchar(120)
+);
+ALTER TABLE sms
+	OWNER TO "schedule";
@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"weavelab.xyz/goils/resources"
+)
+
+// column is a column definition with the constraints declared on it
+type column struct {
+	attr       resources.Attribute
+	primaryKey bool
+	uniques    []resources.UniqueConstraint
+	checks     []resources.CheckConstraint
+}
+
+// addTo appends the column and its constraints to table
+func (col column) addTo(table *resources.CreateTable) {
+	table.Attributes = append(table.Attributes, col.attr)
+	if col.primaryKey {
+		table.PrimaryKey = []string{col.attr.Name}
+	}
+	table.UniqueConstraints = append(table.UniqueConstraints, col.uniques...)
+	for _, check := range col.checks {
+		if check.Name == "" {
+			check.Name = checkName(table, check.Expression)
+		}
+		table.CheckConstraints = append(table.CheckConstraints, check)
+	}
+}
+
+// tableConstraint adds the PRIMARY KEY, FOREIGN KEY, UNIQUE or CHECK
+// constraint element to table, the other constraints (and foreign keys of
+// several columns) are not modelled. Unnamed constraints get the name postgres
+// gives them (i.e. sms_text_key)
+func tableConstraint(table *resources.CreateTable, element []token) error {
+	c := &cursor{tokens: element}
+
+	name := ""
+	if c.accept("CONSTRAINT") {
+		var err error
+		if name, err = c.name(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case c.accept("PRIMARY", "KEY"):
+		columns, err := constraintColumns(c)
+		if err != nil {
+			return err
+		}
+
+		table.PrimaryKey = columns
+		for _, column := range columns {
+			if i, ok := findAttribute(table, column); ok {
+				table.Attributes[i].Nullable = false
+			}
+		}
+	case c.accept("FOREIGN", "KEY"):
+		columns, err := constraintColumns(c)
+		if err != nil {
+			return err
+		}
+
+		if !c.accept("REFERENCES") {
+			return fmt.Errorf("line %d: expected REFERENCES after FOREIGN KEY", element[0].line)
+		}
+
+		ref, err := reference(c)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := findAttribute(table, columns[0]); ok && len(columns) == 1 {
+			table.Attributes[i].References = ref
+		}
+	case c.accept("UNIQUE"):
+		columns, err := constraintColumns(c)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			name = constraintName(table, fmt.Sprintf("%s_%s_key", table.TableName, strings.Join(columns, "_")))
+		}
+		table.UniqueConstraints = append(table.UniqueConstraints, resources.UniqueConstraint{Name: name, Columns: columns})
+	case c.accept("CHECK"):
+		expression, err := checkExpression(c)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			name = checkName(table, expression)
+		}
+		table.CheckConstraints = append(table.CheckConstraints, resources.CheckConstraint{Name: name, Expression: expression})
+	}
+
+	return nil
+}
+
+// reference reads the referenced table and column of REFERENCES and its ON
+// DELETE and ON UPDATE actions, MATCH and DEFERRABLE are not modelled
+func reference(c *cursor) (*resources.Reference, error) {
+	table, err := c.name()
+	if err != nil {
+		return nil, err
+	}
+	ref := &resources.Reference{Table: table}
+
+	if c.peek().is("(") {
+		columns, err := constraintColumns(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if columns[0] != "id" {
+			ref.Column = columns[0]
+		}
+	}
+
+	for {
+		switch {
+		case c.accept("ON", "DELETE"):
+			ref.OnDelete = referentialAction(c)
+		case c.accept("ON", "UPDATE"):
+			ref.OnUpdate = referentialAction(c)
+		case c.accept("MATCH"):
+			c.next()
+		default:
+			return ref, nil
+		}
+	}
+}
+
+func referentialAction(c *cursor) string {
+	for _, action := range resources.ReferentialActions {
+		if c.accept(strings.Fields(action)...) {
+			return action
+		}
+	}
+
+	return strings.ToUpper(c.next().text)
+}
+
+// constraintColumns reads the parenthesised column list of a constraint
+func constraintColumns(c *cursor) ([]string, error) {
+	groups, err := c.group()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(groups))
+	for i, group := range groups {
+		columns[i] = group[0].text
+	}
+
+	return columns, nil
+}
+
+// constraintName returns name, numbered when the table already has a
+// constraint named so (i.e. sms_check1)
+func constraintName(table *resources.CreateTable, name string) string {
+	taken := map[string]bool{}
+	for _, unique := range table.UniqueConstraints {
+		taken[unique.Name] = true
+	}
+	for _, check := range table.CheckConstraints {
+		taken[check.Name] = true
+	}
+
+	numbered := name
+	for i := 1; taken[numbered]; i++ {
+		numbered = fmt.Sprintf("%s%d", name, i)
+	}
+
+	return numbered
+}
+
+// checkName is the name postgres gives an unnamed CHECK, i.e. sms_text_check
+// when the expression uses the text column only and sms_check otherwise
+func checkName(table *resources.CreateTable, expression string) string {
+	var columns []string
+	for _, column := range expressionColumns(expression) {
+		if _, ok := findAttribute(table, column); ok {
+			columns = append(columns, column)
+		}
+	}
+
+	if len(columns) == 1 {
+		return constraintName(table, fmt.Sprintf("%s_%s_check", table.TableName, columns[0]))
+	}
+
+	return constraintName(table, table.TableName+"_check")
+}
+
+// expressionColumns lists the names an expression refers to once each,
+// function names are left out
+func expressionColumns(expression string) []string {
+	tokens, err := tokenize(expression, 0)
+	if err != nil {
+		return nil
+	}
+
+	var columns []string
+	for i, t := range tokens {
+		if t.kind != identToken || (i+1 < len(tokens) && tokens[i+1].is("(")) {
+			continue
+		}
+		if indexOf(columns, t.text) < 0 {
+			columns = append(columns, t.text)
+		}
+	}
+
+	return columns
+}
+
+// checkExpression reads the parenthesised expression of a CHECK
+func checkExpression(c *cursor) (string, error) {
+	groups, err := c.group()
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		parts[i] = joinTokens(group)
+	}
+
+	return strings.Join(parts, ", "), nil
+}
+
+// dropConstraint removes the constraint named name, the ones that are not
+// modelled are ignored. The primary and foreign keys are only known by the
+// names postgres gives them (i.e. sms_pkey and sms_locationid_fkey)
+func dropConstraint(table *resources.CreateTable, name string) {
+	if name == table.TableName+"_pkey" {
+		table.PrimaryKey = nil
+		return
+	}
+
+	for i, attr := range table.Attributes {
+		if name == fmt.Sprintf("%s_%s_fkey", table.TableName, attr.Name) {
+			table.Attributes[i].References = nil
+			return
+		}
+	}
+
+	for i, unique := range table.UniqueConstraints {
+		if unique.Name == name {
+			table.UniqueConstraints = append(table.UniqueConstraints[:i:i], table.UniqueConstraints[i+1:]...)
+			return
+		}
+	}
+
+	for i, check := range table.CheckConstraints {
+		if check.Name == name {
+			table.CheckConstraints = append(table.CheckConstraints[:i:i], table.CheckConstraints[i+1:]...)
+			return
+		}
+	}
+}
+
+func renameConstraint(table *resources.CreateTable, from string, to string) {
+	for i := range table.UniqueConstraints {
+		if table.UniqueConstraints[i].Name == from {
+			table.UniqueConstraints[i].Name = to
+		}
+	}
+
+	for i := range table.CheckConstraints {
+		if table.CheckConstraints[i].Name == from {
+			table.CheckConstraints[i].Name = to
+		}
+	}
+}
+
+// dropConstraintsOn removes the primary key, unique and check constraints
+// using column, as postgres does when a column is dropped
+func dropConstraintsOn(table *resources.CreateTable, column string) {
+	if indexOf(table.PrimaryKey, column) >= 0 {
+		table.PrimaryKey = nil
+	}
+
+	var kept []resources.UniqueConstraint
+	for _, unique := range table.UniqueConstraints {
+		if indexOf(unique.Columns, column) < 0 {
+			kept = append(kept, unique)
+		}
+	}
+	table.UniqueConstraints = kept
+
+	var checks []resources.CheckConstraint
+	for _, check := range table.CheckConstraints {
+		if indexOf(expressionColumns(check.Expression), column) < 0 {
+			checks = append(checks, check)
+		}
+	}
+	table.CheckConstraints = checks
+}
@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	gotoken "go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"weavelab.xyz/goils/resources"
+)
+
+// goTypes maps go field types to the AttributeType of their column
+var goTypes = map[string]resources.AttributeType{
+	"uuid.UUID":       "UUID",
+	"string":          "string",
+	"bool":            "boolean",
+	"time.Time":       "timestamptz",
+	"int":             "bigint",
+	"int64":           "bigint",
+	"int32":           "integer",
+	"int16":           "smallint",
+	"float64":         "double precision",
+	"float32":         "real",
+	"[]byte":          "bytea",
+	"json.RawMessage": "jsonb",
+	"net.IP":          "inet",
+	"pqtype.Inet":     "inet",
+}
+
+// goNullTypes maps database/sql null types to the AttributeType of their column
+var goNullTypes = map[string]resources.AttributeType{
+	"sql.NullString":  "string",
+	"sql.NullBool":    "boolean",
+	"sql.NullTime":    "timestamptz",
+	"sql.NullInt64":   "bigint",
+	"sql.NullInt32":   "integer",
+	"sql.NullFloat64": "double precision",
+	"uuid.NullUUID":   "UUID",
+}
+
+// ParseStruct reads the go source file at path and derives a resource from
+// the struct named name (either the go name, i.e. "Setting", or its table
+// name, i.e. "setting")
+//
+// Columns are named by the field's `db` tag (or the snake_case field name),
+// pointer and sql.Null* fields are nullable and the `goils` tag adds options:
+//
+//	LocationID uuid.UUID `db:"locationid" goils:"index=idx_setting_location_id"`
+//	Note       string    `goils:"type=text,nullable"`
+func ParseStruct(path string, name string) (resources.Resource, error) {
+	fset := gotoken.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return resources.Resource{}, err
+	}
+
+	spec := findStruct(file, name)
+	if spec == nil {
+		return resources.Resource{}, fmt.Errorf("%s: struct %q not found", path, name)
+	}
+
+	resource := resources.Resource{
+		CreateTable: resources.CreateTable{
+			TableName:  strcase.ToSnake(spec.Name.Name),
+			Attributes: make(resources.Attributes, 0),
+		},
+		Package: file.Name.Name,
+	}
+
+	for _, field := range spec.Type.(*ast.StructType).Fields.List {
+		if err := addField(&resource, field); err != nil {
+			return resource, fmt.Errorf("%s: %v", fset.Position(field.Pos()), err)
+		}
+	}
+
+	return resource, nil
+}
+
+func findStruct(file *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != gotoken.TYPE {
+			continue
+		}
+
+		for _, s := range gen.Specs {
+			spec := s.(*ast.TypeSpec)
+			if _, ok := spec.Type.(*ast.StructType); !ok {
+				continue
+			}
+
+			if spec.Name.Name == name || strcase.ToSnake(spec.Name.Name) == name {
+				return spec
+			}
+		}
+	}
+
+	return nil
+}
+
+// addField adds the columns (and index columns) described by field to resource
+func addField(resource *resources.Resource, field *ast.Field) error {
+	tag := reflect.StructTag("")
+	if field.Tag != nil {
+		value, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return err
+		}
+		tag = reflect.StructTag(value)
+	}
+
+	column := strings.Split(tag.Get("db"), ",")[0]
+	if column == "-" {
+		return nil
+	}
+
+	options := parseGoilsTag(tag.Get("goils"))
+
+	for _, ident := range field.Names {
+		if !ident.IsExported() {
+			continue
+		}
+
+		attr := resources.Attribute{
+			Name: column,
+		}
+		if attr.Name == "" {
+			attr.Name = strcase.ToSnake(ident.Name)
+		}
+
+		typ, nullable, err := fieldType(field.Type)
+		if err != nil && options["type"] == "" {
+			return fmt.Errorf("%s: %v", ident.Name, err)
+		}
+		attr.Type = typ
+		attr.Nullable = nullable
+
+		if t, ok := options["type"]; ok {
+			attr.Type = resources.AttributeType(t)
+		}
+		if _, ok := options["nullable"]; ok {
+			attr.Nullable = true
+		}
+
+		resource.Attributes = append(resource.Attributes, attr)
+
+		if index, ok := options["index"]; ok {
+			addIndexColumn(resource, index, attr.Name)
+		}
+	}
+
+	return nil
+}
+
+// fieldType returns the AttributeType for a field type and whether it is nullable
+func fieldType(expr ast.Expr) (resources.AttributeType, bool, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		typ, _, err := fieldType(star.X)
+		return typ, true, err
+	}
+
+	name := exprString(expr)
+	if typ, ok := goTypes[name]; ok {
+		return typ, false, nil
+	}
+
+	if typ, ok := goNullTypes[name]; ok {
+		return typ, true, nil
+	}
+
+	if array, ok := expr.(*ast.ArrayType); ok && array.Len == nil {
+		typ, _, err := fieldType(array.Elt)
+		if err != nil {
+			return "", false, err
+		}
+
+		return typ + "[]", false, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported field type %s", name)
+}
+
+func exprString(expr ast.Expr) string {
+	buf := bytes.Buffer{}
+	printer.Fprint(&buf, gotoken.NewFileSet(), expr)
+
+	return buf.String()
+}
+
+// parseGoilsTag splits a `goils:"index=idx_x,nullable"` tag into its options
+func parseGoilsTag(tag string) map[string]string {
+	options := map[string]string{}
+	for _, option := range strings.Split(tag, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) == 1 {
+			options[parts[0]] = ""
+			continue
+		}
+		options[parts[0]] = parts[1]
+	}
+
+	return options
+}
+
+// addIndexColumn appends column to the index named name, creating the index if needed
+func addIndexColumn(resource *resources.Resource, name string, column string) {
+	for i := range resource.Indexes {
+		if resource.Indexes[i].Name == name {
+			resource.Indexes[i].Columns = append(resource.Indexes[i].Columns, column)
+			return
+		}
+	}
+
+	resource.Indexes = append(resource.Indexes, resources.Index{
+		Name:    name,
+		Type:    "BTREE",
+		Columns: []string{column},
+	})
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func TestParseStruct(t *testing.T) {
+	want := resources.Resource{
+		CreateTable: resources.CreateTable{
+			TableName: "setting",
+			Attributes: resources.Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID"},
+				{Name: "name", Type: "string"},
+				{Name: "note", Type: "text", Nullable: true},
+				{Name: "priority", Type: "integer", Nullable: true},
+				{Name: "tags", Type: "string[]"},
+				{Name: "options", Type: "jsonb"},
+				{Name: "created_at", Type: "timestamptz"},
+			},
+			Indexes: resources.Indexes{
+				{
+					Name:    "idx_setting_location_id",
+					Type:    "BTREE",
+					Columns: []string{"id", "locationid"},
+				},
+				{
+					Name:    "idx_setting_created_at",
+					Type:    "BTREE",
+					Columns: []string{"created_at"},
+				},
+			},
+		},
+		Package: "models",
+	}
+
+	tests := []struct {
+		name     string
+		typeName string
+	}{
+		{
+			name:     "finds the struct by its go name",
+			typeName: "Setting",
+		},
+		{
+			name:     "finds the struct by its table name",
+			typeName: "setting",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseStruct", t, func() {
+				got, err := ParseStruct("testdata/setting.go", tt.typeName)
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, want)
+			})
+		})
+	}
+
+	Convey("ParseStruct fails on unsupported field types", t, func() {
+		_, err := ParseStruct("testdata/setting.go", "Broken")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "setting.go:29:2: Lookup: unsupported field type map[string]int")
+	})
+
+	Convey("ParseStruct fails when the struct is missing", t, func() {
+		_, err := ParseStruct("testdata/setting.go", "Appointment")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	gooseUp    = "-- +goose Up"
+	gooseDown  = "-- +goose Down"
+	dbmateUp   = "-- migrate:up"
+	dbmateDown = "-- migrate:down"
+)
+
+// migration is a migration file applying its changes (i.e. not a down or
+// undo migration)
+type migration struct {
+	version int64
+	path    string
+}
+
+// ReplayMigrations applies the Up section of every migration in dir, in version
+// order, and returns the resulting schema. Goose, dbmate, golang-migrate,
+// flyway and atlas migrations are read, files without up and down sections
+// being applied entirely
+func ReplayMigrations(dir string) (*Schema, error) {
+	migrations, err := findMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := NewSchema()
+	for _, m := range migrations {
+		data, err := ioutil.ReadFile(m.path)
+		if err != nil {
+			return nil, err
+		}
+
+		statements, err := parseStatements(upSection(string(data)), 1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", m.path, err)
+		}
+
+		for _, stmt := range statements {
+			if err := schema.Exec(stmt); err != nil {
+				return nil, fmt.Errorf("%s: %v", m.path, err)
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// findMigrations lists the migrations in dir ordered by version, the version
+// being the number the file name starts with (i.e. 20200615120000_create_sms_table.sql
+// or V2__create_sms_table.sql). Down and undo migrations are skipped
+func findMigrations(dir string) ([]migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := file.Name()
+		switch filepath.Ext(name) {
+		case ".sql":
+		case ".go":
+			return nil, fmt.Errorf("%s: go migrations cannot be replayed", filepath.Join(dir, name))
+		default:
+			continue
+		}
+
+		if strings.HasSuffix(name, ".down.sql") || isUndoMigration(name) {
+			continue
+		}
+
+		version, err := strconv.ParseInt(strings.SplitN(strings.TrimPrefix(name, "V"), "_", 2)[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: file name does not start with a version", filepath.Join(dir, name))
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			path:    filepath.Join(dir, name),
+		})
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// isUndoMigration returns whether name is a flyway undo migration (i.e.
+// U2__create_sms_table.sql)
+func isUndoMigration(name string) bool {
+	return len(name) > 1 && name[0] == 'U' && name[1] >= '0' && name[1] <= '9'
+}
+
+// upSection blanks every line outside of the "-- +goose Up" or "-- migrate:up"
+// section of src, keeping line numbers intact for errors. src without sections
+// is kept whole
+func upSection(src string) string {
+	if !strings.Contains(src, gooseUp) && !strings.Contains(src, dbmateUp) {
+		return src
+	}
+
+	lines := strings.Split(src, "\n")
+	up := false
+
+	for i, line := range lines {
+		annotation := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(annotation, gooseUp), strings.HasPrefix(annotation, dbmateUp):
+			up = true
+		case strings.HasPrefix(annotation, gooseDown), strings.HasPrefix(annotation, dbmateDown):
+			up = false
+		}
+
+		if !up {
+			lines[i] = ""
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func TestReplayMigrations(t *testing.T) {
+	Convey("ReplayMigrations applies the up sections in version order", t, func() {
+		schema, err := ReplayMigrations("testdata/migrations")
+		So(err, ShouldBeNil)
+		So(schema.Tables(), ShouldResemble, []resources.CreateTable{
+			{
+				TableName: "sms",
+				Attributes: resources.Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "location_id", Type: "UUID"},
+					{Name: "text", Type: "text", Nullable: true},
+					{Name: "created_at", Type: "timestamptz", Default: "now()"},
+				},
+				Indexes: resources.Indexes{
+					{
+						Name:    "idx_sms_location",
+						Type:    "BTREE",
+						Columns: []string{"location_id", "id"},
+					},
+				},
+				Owner: "schedule",
+			},
+			{
+				TableName: "setting",
+				Attributes: resources.Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "name", Type: "text", Nullable: true},
+				},
+				Indexes: resources.Indexes{},
+			},
+		})
+	})
+
+	Convey("ReplayMigrations applies generated alter migrations", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		previous := resources.Resource{CreateTable: resources.CreateTable{
+			TableName: "sms",
+			Attributes: resources.Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text"},
+				{Name: "sent", Type: "boolean"},
+			},
+		}}
+		resource := resources.Resource{CreateTable: resources.CreateTable{
+			TableName: "sms",
+			Attributes: resources.Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "location_id", Type: "UUID", References: &resources.Reference{Table: "location"}},
+			},
+			Indexes:    resources.Indexes{},
+			PrimaryKey: []string{"id"},
+		}}
+
+		resources.MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		defer func() { resources.MockedTime = time.Time{} }()
+
+		create := resources.GenerateMigration(previous)
+		So(create.AnyErrors(), ShouldBeFalse)
+		resources.MockedTime = resources.MockedTime.Add(time.Second)
+		alter := resources.GenerateAlterMigration(previous, resource)
+		So(alter.AnyErrors(), ShouldBeFalse)
+		So(append(create, alter...).CreateFilesIn(dir), ShouldBeNil)
+
+		schema, err := ReplayMigrations(dir)
+		So(err, ShouldBeNil)
+
+		table, _ := schema.Table("sms")
+		resource.Indexes = resource.AllIndexes()
+		So(table, ShouldResemble, resource.CreateTable)
+	})
+
+	Convey("ReplayMigrations reads dbmate, golang-migrate and flyway migrations", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := map[string]string{
+			"20200615120000_create_sms.sql":    "-- migrate:up\nCREATE TABLE sms (id uuid);\n\n-- migrate:down\nDROP TABLE sms;\n",
+			"20200615120001_add_text.up.sql":   "ALTER TABLE sms ADD COLUMN text text;\n",
+			"20200615120001_add_text.down.sql": "ALTER TABLE sms DROP COLUMN text;\n",
+			"V20200615120003__create_tag.sql":  "CREATE TABLE tag (id uuid);\n",
+			"U20200615120003__create_tag.sql":  "DROP TABLE tag;\n",
+		}
+		for name, src := range files {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644), ShouldBeNil)
+		}
+
+		schema, err := ReplayMigrations(dir)
+		So(err, ShouldBeNil)
+
+		sms, ok := schema.Table("sms")
+		So(ok, ShouldBeTrue)
+		So(sms.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID", Nullable: true},
+			{Name: "text", Type: "text", Nullable: true},
+		})
+
+		_, ok = schema.Table("tag")
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("ReplayMigrations reports the migration a statement fails in", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "1_drop_sms.sql")
+		err = ioutil.WriteFile(path, []byte("-- +goose Up\n\nDROP TABLE sms;\n-- +goose Down\n"), 0644)
+		So(err, ShouldBeNil)
+
+		_, err = ReplayMigrations(dir)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, path+`: line 3: drop of unknown table "sms"`)
+	})
+
+	Convey("ReplayMigrations fails on go migrations", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, "1_seed.go"), []byte("package migrations\n"), 0644)
+		So(err, ShouldBeNil)
+
+		_, err = ReplayMigrations(dir)
+		So(err, ShouldNotBeNil)
+	})
+}
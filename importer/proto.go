@@ -0,0 +1,361 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/scanner"
+
+	"github.com/iancoleman/strcase"
+
+	"weavelab.xyz/goils/resources"
+)
+
+// protoTypes maps proto field types to the AttributeType of their column
+var protoTypes = map[string]resources.AttributeType{
+	"shared.UUID":               "UUID",
+	"string":                    "string",
+	"bool":                      "boolean",
+	"int32":                     "integer",
+	"sint32":                    "integer",
+	"sfixed32":                  "integer",
+	"int64":                     "bigint",
+	"sint64":                    "bigint",
+	"sfixed64":                  "bigint",
+	"uint32":                    "bigint",
+	"fixed32":                   "bigint",
+	"uint64":                    "numeric",
+	"fixed64":                   "numeric",
+	"float":                     "real",
+	"double":                    "double precision",
+	"bytes":                     "bytea",
+	"google.protobuf.Timestamp": "timestamptz",
+	"google.protobuf.Duration":  "interval",
+	"google.protobuf.Struct":    "jsonb",
+}
+
+// protoWrappers maps the well-known wrapper types to the AttributeType of their nullable column
+var protoWrappers = map[string]resources.AttributeType{
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.BoolValue":   "boolean",
+	"google.protobuf.Int32Value":  "integer",
+	"google.protobuf.Int64Value":  "bigint",
+	"google.protobuf.UInt32Value": "bigint",
+	"google.protobuf.UInt64Value": "numeric",
+	"google.protobuf.FloatValue":  "real",
+	"google.protobuf.DoubleValue": "double precision",
+	"google.protobuf.BytesValue":  "bytea",
+}
+
+// protoField is a field of a proto message
+type protoField struct {
+	name     string
+	typ      string
+	label    string // optional or repeated
+	oneof    bool
+	position scanner.Position
+}
+
+// protoMessage is a top level message of a proto file
+type protoMessage struct {
+	name   string
+	fields []protoField
+}
+
+// protoFile is the subset of a proto file goils reads
+type protoFile struct {
+	pkg       string
+	goPackage string
+	messages  []protoMessage
+	enums     map[string]bool
+}
+
+// ParseProto reads the proto3 file at path and derives a resource from the
+// message named name (either the message name, i.e. "Setting", or its table
+// name, i.e. "setting"). The package is taken from go_package, falling back
+// to the proto package
+func ParseProto(path string, name string) (resources.Resource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return resources.Resource{}, err
+	}
+	defer f.Close()
+
+	p := newProtoParser(f, path)
+	file, err := p.file()
+	if err != nil {
+		return resources.Resource{}, err
+	}
+
+	for _, message := range file.messages {
+		if message.name != name && strcase.ToSnake(message.name) != name {
+			continue
+		}
+
+		resource := resources.Resource{
+			CreateTable: resources.CreateTable{
+				TableName:  strcase.ToSnake(message.name),
+				Attributes: make(resources.Attributes, 0),
+			},
+			Package: file.packageName(),
+		}
+
+		for _, field := range message.fields {
+			attr, err := file.attribute(field)
+			if err != nil {
+				return resource, fmt.Errorf("%s: %v", field.position, err)
+			}
+			resource.Attributes = append(resource.Attributes, attr)
+		}
+
+		return resource, nil
+	}
+
+	return resources.Resource{}, fmt.Errorf("%s: message %q not found", path, name)
+}
+
+// packageName returns the go package name of the file
+func (f protoFile) packageName() string {
+	if f.goPackage != "" {
+		if i := strings.LastIndex(f.goPackage, ";"); i >= 0 {
+			return f.goPackage[i+1:]
+		}
+
+		return path.Base(f.goPackage)
+	}
+
+	parts := strings.Split(f.pkg, ".")
+	return parts[len(parts)-1]
+}
+
+// attribute maps a message field to its column
+func (f protoFile) attribute(field protoField) (resources.Attribute, error) {
+	attr := resources.Attribute{
+		Name:     strcase.ToSnake(field.name),
+		Nullable: field.label == "optional" || field.oneof,
+	}
+
+	typ := strings.TrimPrefix(field.typ, ".")
+	switch {
+	case strings.HasPrefix(typ, "map<"):
+		attr.Type = "jsonb"
+	case protoTypes[typ] != "":
+		attr.Type = protoTypes[typ]
+	case protoWrappers[typ] != "":
+		attr.Type = protoWrappers[typ]
+		attr.Nullable = true
+	case f.enums[typ]:
+		attr.Type = "text"
+	default:
+		return attr, fmt.Errorf("%s: unsupported field type %s", field.name, field.typ)
+	}
+
+	if field.label == "repeated" {
+		attr.Type += "[]"
+	}
+
+	return attr, nil
+}
+
+type protoParser struct {
+	s    scanner.Scanner
+	tok  rune
+	errs []string
+}
+
+func newProtoParser(f *os.File, filename string) *protoParser {
+	p := &protoParser{}
+	p.s.Init(f)
+	p.s.Filename = filename
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats |
+		scanner.ScanStrings | scanner.ScanRawStrings | scanner.ScanComments | scanner.SkipComments
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		p.errs = append(p.errs, fmt.Sprintf("%s: %s", s.Position, msg))
+	}
+	p.next()
+
+	return p
+}
+
+func (p *protoParser) next() {
+	p.tok = p.s.Scan()
+}
+
+func (p *protoParser) text() string {
+	return p.s.TokenText()
+}
+
+func (p *protoParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", p.s.Position, fmt.Sprintf(format, args...))
+}
+
+func (p *protoParser) expect(text string) error {
+	if p.text() != text {
+		return p.errorf("expected %q, got %q", text, p.text())
+	}
+	p.next()
+
+	return nil
+}
+
+// fullIdent reads a dotted name (i.e. google.protobuf.Timestamp)
+func (p *protoParser) fullIdent() string {
+	name := ""
+	for {
+		if p.tok == '.' {
+			name += "."
+			p.next()
+		}
+		if p.tok != scanner.Ident {
+			return name
+		}
+
+		name += p.text()
+		p.next()
+		if p.tok != '.' {
+			return name
+		}
+	}
+}
+
+// skipStatement skips to the end of the current statement or block
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for p.tok != scanner.EOF {
+		switch p.tok {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth <= 0 {
+				p.next()
+				return
+			}
+		case ';':
+			if depth == 0 {
+				p.next()
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+func (p *protoParser) file() (protoFile, error) {
+	file := protoFile{
+		enums: map[string]bool{},
+	}
+
+	for p.tok != scanner.EOF {
+		switch p.text() {
+		case "package":
+			p.next()
+			file.pkg = p.fullIdent()
+			p.skipStatement()
+		case "option":
+			p.next()
+			option := p.fullIdent()
+			if option == "go_package" && p.expect("=") == nil && p.tok == scanner.String {
+				file.goPackage = strings.Trim(p.text(), `"`)
+			}
+			p.skipStatement()
+		case "message":
+			p.next()
+			message, err := p.message(file.enums)
+			if err != nil {
+				return file, err
+			}
+			file.messages = append(file.messages, message)
+		case "enum":
+			p.next()
+			file.enums[p.text()] = true
+			p.skipStatement()
+		default:
+			p.skipStatement()
+		}
+	}
+
+	if len(p.errs) > 0 {
+		return file, fmt.Errorf("%s", strings.Join(p.errs, "\n"))
+	}
+
+	return file, nil
+}
+
+func (p *protoParser) message(enums map[string]bool) (protoMessage, error) {
+	message := protoMessage{name: p.text()}
+	p.next()
+
+	if err := p.expect("{"); err != nil {
+		return message, err
+	}
+
+	for oneof := false; ; {
+		switch p.text() {
+		case "}":
+			p.next()
+			if !oneof {
+				return message, nil
+			}
+			oneof = false
+		case "oneof":
+			p.next()
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return message, err
+			}
+			oneof = true
+		case "enum":
+			p.next()
+			enums[p.text()] = true
+			enums[message.name+"."+p.text()] = true
+			p.skipStatement()
+		case "message", "option", "reserved", "extensions", "extend":
+			p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			if p.tok == scanner.EOF {
+				return message, p.errorf("message %s is not closed", message.name)
+			}
+
+			field, err := p.field()
+			if err != nil {
+				return message, err
+			}
+			field.oneof = oneof
+			message.fields = append(message.fields, field)
+		}
+	}
+}
+
+// field reads "[optional|repeated] type name = number [options];"
+func (p *protoParser) field() (protoField, error) {
+	field := protoField{position: p.s.Position}
+
+	if p.text() == "optional" || p.text() == "repeated" {
+		field.label = p.text()
+		p.next()
+	}
+
+	if p.text() == "map" {
+		field.typ = "map<"
+		for p.tok != '>' && p.tok != scanner.EOF {
+			p.next()
+		}
+		p.next()
+	} else {
+		field.typ = p.fullIdent()
+	}
+
+	if p.tok != scanner.Ident {
+		return field, p.errorf("expected a field name, got %q", p.text())
+	}
+	field.name = p.text()
+	p.next()
+
+	p.skipStatement()
+
+	return field, nil
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func TestParseProto(t *testing.T) {
+	Convey("ParseProto derives a resource from a message", t, func() {
+		got, err := ParseProto("testdata/setting.proto", "setting")
+		So(err, ShouldBeNil)
+		So(got, ShouldResemble, resources.Resource{
+			CreateTable: resources.CreateTable{
+				TableName: "setting",
+				Attributes: resources.Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "location_id", Type: "UUID"},
+					{Name: "name", Type: "string"},
+					{Name: "note", Type: "string", Nullable: true},
+					{Name: "priority", Type: "integer", Nullable: true},
+					{Name: "tags", Type: "string[]"},
+					{Name: "labels", Type: "jsonb"},
+					{Name: "kind", Type: "text"},
+					{Name: "created_at", Type: "timestamptz"},
+					{Name: "max_count", Type: "bigint", Nullable: true},
+					{Name: "max_ratio", Type: "double precision", Nullable: true},
+				},
+			},
+			Package: "settingspb",
+		})
+	})
+
+	Convey("ParseProto fails on message fields", t, func() {
+		_, err := ParseProto("testdata/setting.proto", "Broken")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "testdata/setting.proto:34:3: parent: unsupported field type Setting")
+	})
+
+	Convey("ParseProto fails when the message is missing", t, func() {
+		_, err := ParseProto("testdata/setting.proto", "Location")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,836 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"weavelab.xyz/goils/resources"
+)
+
+// Schema is an in-memory model of the tables (and enum types) built by SQL statements
+type Schema struct {
+	tables []resources.CreateTable
+	enums  map[string][]string
+}
+
+// NewSchema returns an empty schema
+func NewSchema() *Schema {
+	return &Schema{
+		tables: make([]resources.CreateTable, 0),
+		enums:  map[string][]string{},
+	}
+}
+
+// Tables returns every table in the order they were created
+func (s *Schema) Tables() []resources.CreateTable {
+	tables := make([]resources.CreateTable, len(s.tables))
+	for i, table := range s.tables {
+		tables[i] = s.withEnums(table)
+	}
+
+	return tables
+}
+
+// Table returns the table named name
+func (s *Schema) Table(name string) (resources.CreateTable, bool) {
+	if t := s.table(name); t != nil {
+		return s.withEnums(*t), true
+	}
+
+	return resources.CreateTable{}, false
+}
+
+// withEnums returns table with the values of its enum columns
+func (s *Schema) withEnums(table resources.CreateTable) resources.CreateTable {
+	attributes := make(resources.Attributes, len(table.Attributes))
+	for i, attr := range table.Attributes {
+		if values, ok := s.enums[string(attr.Type)]; ok {
+			attr.Values = append([]string{}, values...)
+		}
+		attributes[i] = attr
+	}
+	table.Attributes = attributes
+
+	return table
+}
+
+func (s *Schema) table(name string) *resources.CreateTable {
+	for i := range s.tables {
+		if s.tables[i].TableName == name {
+			return &s.tables[i]
+		}
+	}
+
+	return nil
+}
+
+// Exec applies stmt to the schema, statements goils does not model are ignored
+func (s *Schema) Exec(stmt statement) error {
+	c := &cursor{tokens: stmt.tokens}
+
+	switch {
+	case c.accept("CREATE", "TABLE"):
+		return s.createTable(c, stmt.line)
+	case c.accept("CREATE", "INDEX"), c.accept("CREATE", "UNIQUE", "INDEX"):
+		return s.createIndex(c, stmt.line)
+	case c.accept("ALTER", "TABLE"):
+		return s.alterTable(c, stmt.line)
+	case c.accept("ALTER", "INDEX"):
+		return s.alterIndex(c, stmt.line)
+	case c.accept("DROP", "TABLE"):
+		return s.dropTable(c, stmt.line)
+	case c.accept("DROP", "INDEX"):
+		return s.dropIndex(c, stmt.line)
+	case c.accept("CREATE", "TYPE"):
+		return s.createType(c, stmt.line)
+	case c.accept("ALTER", "TYPE"):
+		return s.alterType(c, stmt.line)
+	case c.accept("DROP", "TYPE"):
+		return s.dropType(c, stmt.line)
+	}
+
+	return nil
+}
+
+func (s *Schema) createTable(c *cursor, line int) error {
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	if s.table(name) != nil {
+		if ifNotExists {
+			return nil
+		}
+
+		return fmt.Errorf("line %d: table %q already exists", line, name)
+	}
+
+	elements, err := c.group()
+	if err != nil {
+		return err
+	}
+
+	table := resources.CreateTable{
+		TableName:  name,
+		Attributes: make(resources.Attributes, 0),
+		Indexes:    make(resources.Indexes, 0),
+	}
+
+	for _, element := range elements {
+		if isTableConstraint(element[0]) {
+			if err := tableConstraint(&table, element); err != nil {
+				return err
+			}
+			continue
+		}
+
+		col, err := parseColumn(name, element)
+		if err != nil {
+			return err
+		}
+		col.addTo(&table)
+	}
+
+	s.tables = append(s.tables, table)
+
+	return nil
+}
+
+func (s *Schema) createIndex(c *cursor, line int) error {
+	c.accept("CONCURRENTLY")
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	if table, _ := s.index(name); table != nil {
+		if ifNotExists {
+			return nil
+		}
+
+		return fmt.Errorf("line %d: index %q already exists", line, name)
+	}
+
+	if !c.accept("ON") {
+		return fmt.Errorf("line %d: expected ON after index %q", line, name)
+	}
+	c.accept("ONLY")
+
+	tableName, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	table := s.table(tableName)
+	if table == nil {
+		return fmt.Errorf("line %d: index %q is on unknown table %q", line, name, tableName)
+	}
+
+	index := resources.Index{
+		Name: name,
+		Type: "BTREE",
+	}
+	if c.accept("USING") {
+		index.Type = strings.ToUpper(c.next().text)
+	}
+
+	elements, err := c.group()
+	if err != nil {
+		return err
+	}
+
+	for _, element := range elements {
+		if !isColumnElement(element) {
+			return fmt.Errorf("line %d: index %q is on the expression %s, only column indexes are imported", line, name, joinTokens(element))
+		}
+		index.Columns = append(index.Columns, element[0].text)
+	}
+
+	table.Indexes = append(table.Indexes, index)
+
+	return nil
+}
+
+// isColumnElement returns whether an index element is a column, optionally
+// followed by its collation, operator class and ordering (i.e. name DESC),
+// rather than an expression (i.e. lower(name))
+func isColumnElement(element []token) bool {
+	if element[0].kind != identToken {
+		return false
+	}
+
+	for _, t := range element[1:] {
+		if t.kind == punctToken {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Schema) alterTable(c *cursor, line int) error {
+	ifExists := c.accept("IF", "EXISTS")
+	c.accept("ONLY")
+
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	table := s.table(name)
+	if table == nil {
+		if ifExists {
+			return nil
+		}
+
+		return fmt.Errorf("line %d: alter of unknown table %q", line, name)
+	}
+
+	if c.accept("RENAME", "TO") {
+		renamed, err := c.name()
+		if err != nil {
+			return err
+		}
+		table.TableName = renamed
+
+		return nil
+	}
+
+	if c.accept("RENAME", "CONSTRAINT") {
+		from, err := c.name()
+		if err != nil {
+			return err
+		}
+
+		if !c.accept("TO") {
+			return fmt.Errorf("line %d: expected TO after RENAME CONSTRAINT %s", line, from)
+		}
+
+		to, err := c.name()
+		if err != nil {
+			return err
+		}
+		renameConstraint(table, from, to)
+
+		return nil
+	}
+
+	if c.accept("RENAME") {
+		c.accept("COLUMN")
+
+		return renameColumn(table, c, line)
+	}
+
+	for {
+		if err := alterTableAction(table, c, line); err != nil {
+			return err
+		}
+
+		if !c.accept(",") {
+			return nil
+		}
+	}
+}
+
+// alterTableAction applies a single comma separated action of an ALTER TABLE
+func alterTableAction(table *resources.CreateTable, c *cursor, line int) error {
+	switch {
+	case c.accept("OWNER", "TO"):
+		table.Owner = c.next().text
+	case c.accept("ADD", "COLUMN"), c.peek().is("ADD") && !isTableConstraint(c.peekAt(1)) && c.accept("ADD"):
+		ifNotExists := c.accept("IF", "NOT", "EXISTS")
+
+		col, err := parseColumn(table.TableName, c.element())
+		if err != nil {
+			return err
+		}
+
+		if _, exists := findAttribute(table, col.attr.Name); exists {
+			if ifNotExists {
+				return nil
+			}
+
+			return fmt.Errorf("line %d: column %q of %q already exists", line, col.attr.Name, table.TableName)
+		}
+
+		col.addTo(table)
+	case c.peek().is("ADD") && c.accept("ADD"):
+		return tableConstraint(table, c.element())
+	case c.accept("DROP", "CONSTRAINT"):
+		c.accept("IF", "EXISTS")
+
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		c.element()
+
+		dropConstraint(table, name)
+	case c.accept("DROP", "COLUMN"), c.peek().is("DROP") && !c.peekAt(1).is("CONSTRAINT") && c.accept("DROP"):
+		ifExists := c.accept("IF", "EXISTS")
+
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		c.element()
+
+		i, exists := findAttribute(table, name)
+		if !exists {
+			if ifExists {
+				return nil
+			}
+
+			return fmt.Errorf("line %d: column %q of %q does not exist", line, name, table.TableName)
+		}
+
+		table.Attributes = append(table.Attributes[:i:i], table.Attributes[i+1:]...)
+		dropIndexesOn(table, name)
+		dropConstraintsOn(table, name)
+	case c.peek().is("ALTER") && !c.peekAt(1).is("CONSTRAINT") && c.accept("ALTER"):
+		c.accept("COLUMN")
+
+		return alterColumn(table, c, line)
+	default:
+		// constraints and storage options are not modelled
+		c.element()
+	}
+
+	return nil
+}
+
+// alterColumn applies ALTER COLUMN ... TYPE/SET NOT NULL/DROP NOT NULL/SET DEFAULT/DROP DEFAULT
+func alterColumn(table *resources.CreateTable, c *cursor, line int) error {
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	i, exists := findAttribute(table, name)
+	if !exists {
+		return fmt.Errorf("line %d: column %q of %q does not exist", line, name, table.TableName)
+	}
+	attr := &table.Attributes[i]
+
+	switch {
+	case c.accept("TYPE"), c.accept("SET", "DATA", "TYPE"):
+		typ := make([]token, 0)
+		for _, t := range c.element() {
+			if t.is("USING") || t.is("COLLATE") {
+				break
+			}
+			typ = append(typ, t)
+		}
+		attr.Type = attributeType(joinTokens(typ))
+	case c.accept("SET", "NOT", "NULL"):
+		attr.Nullable = false
+	case c.accept("DROP", "NOT", "NULL"):
+		attr.Nullable = true
+	case c.accept("SET", "DEFAULT"):
+		attr.Default = joinTokens(c.element())
+	case c.accept("DROP", "DEFAULT"):
+		attr.Default = ""
+	case c.accept("DROP", "EXPRESSION"):
+		attr.Generated = ""
+	case c.peek().is("ADD") && c.peekAt(1).is("GENERATED"):
+		c.accept("ADD")
+		identity, err := acceptIdentity(c)
+		if err != nil {
+			return err
+		}
+		attr.Identity, attr.Nullable = identity, false
+	case c.accept("SET", "GENERATED", "ALWAYS"):
+		attr.Identity = resources.IdentityAlways
+	case c.accept("SET", "GENERATED", "BY", "DEFAULT"):
+		attr.Identity = resources.IdentityByDefault
+	case c.accept("DROP", "IDENTITY"):
+		c.accept("IF", "EXISTS")
+		attr.Identity = ""
+	default:
+		c.element()
+	}
+
+	return nil
+}
+
+func renameColumn(table *resources.CreateTable, c *cursor, line int) error {
+	from, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	if !c.accept("TO") {
+		return fmt.Errorf("line %d: expected TO after RENAME COLUMN %s", line, from)
+	}
+
+	to, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	i, exists := findAttribute(table, from)
+	if !exists {
+		return fmt.Errorf("line %d: column %q of %q does not exist", line, from, table.TableName)
+	}
+	table.Attributes[i].Name = to
+
+	for _, index := range table.Indexes {
+		for j, column := range index.Columns {
+			if column == from {
+				index.Columns[j] = to
+			}
+		}
+	}
+
+	for _, unique := range table.UniqueConstraints {
+		for j, column := range unique.Columns {
+			if column == from {
+				unique.Columns[j] = to
+			}
+		}
+	}
+
+	for j, column := range table.PrimaryKey {
+		if column == from {
+			table.PrimaryKey[j] = to
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) alterIndex(c *cursor, line int) error {
+	ifExists := c.accept("IF", "EXISTS")
+
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	table, i := s.index(name)
+	if table == nil {
+		if ifExists {
+			return nil
+		}
+
+		return fmt.Errorf("line %d: alter of unknown index %q", line, name)
+	}
+
+	if c.accept("RENAME", "TO") {
+		if table.Indexes[i].Name, err = c.name(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) dropTable(c *cursor, line int) error {
+	ifExists := c.accept("IF", "EXISTS")
+
+	for {
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+
+		table := s.table(name)
+		switch {
+		case table != nil:
+			for i := range s.tables {
+				if &s.tables[i] == table {
+					s.tables = append(s.tables[:i:i], s.tables[i+1:]...)
+					break
+				}
+			}
+		case !ifExists:
+			return fmt.Errorf("line %d: drop of unknown table %q", line, name)
+		}
+
+		if !c.accept(",") {
+			return nil
+		}
+	}
+}
+
+func (s *Schema) dropIndex(c *cursor, line int) error {
+	c.accept("CONCURRENTLY")
+	ifExists := c.accept("IF", "EXISTS")
+
+	for {
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+
+		table, i := s.index(name)
+		switch {
+		case table != nil:
+			table.Indexes = append(table.Indexes[:i:i], table.Indexes[i+1:]...)
+		case !ifExists:
+			return fmt.Errorf("line %d: drop of unknown index %q", line, name)
+		}
+
+		if !c.accept(",") {
+			return nil
+		}
+	}
+}
+
+// createType records enum types, other types are not modelled
+func (s *Schema) createType(c *cursor, line int) error {
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	if !c.accept("AS", "ENUM") {
+		return nil
+	}
+
+	if _, ok := s.enums[name]; ok {
+		return fmt.Errorf("line %d: type %q already exists", line, name)
+	}
+
+	elements, err := c.group()
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if len(element) != 1 || element[0].kind != stringToken {
+			return fmt.Errorf("line %d: enum %q values must be strings", line, name)
+		}
+		values = append(values, element[0].text)
+	}
+	s.enums[name] = values
+
+	return nil
+}
+
+// alterType handles ADD VALUE [IF NOT EXISTS] 'value' [BEFORE | AFTER 'value'] and RENAME VALUE of enums
+func (s *Schema) alterType(c *cursor, line int) error {
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+
+	values, ok := s.enums[name]
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case c.accept("ADD", "VALUE"):
+		ifNotExists := c.accept("IF", "NOT", "EXISTS")
+		value := c.next().text
+		if indexOf(values, value) >= 0 {
+			if ifNotExists {
+				return nil
+			}
+
+			return fmt.Errorf("line %d: enum %q already has value %q", line, name, value)
+		}
+
+		at := len(values)
+		switch {
+		case c.accept("BEFORE"):
+			at = indexOf(values, c.next().text)
+		case c.accept("AFTER"):
+			if at = indexOf(values, c.next().text); at >= 0 {
+				at++
+			}
+		}
+		if at < 0 {
+			return fmt.Errorf("line %d: enum %q has no such value", line, name)
+		}
+
+		values = append(values[:at:at], append([]string{value}, values[at:]...)...)
+	case c.accept("RENAME", "VALUE"):
+		from := c.next().text
+		if !c.accept("TO") {
+			return fmt.Errorf("line %d: expected TO", line)
+		}
+
+		i := indexOf(values, from)
+		if i < 0 {
+			return fmt.Errorf("line %d: enum %q has no value %q", line, name, from)
+		}
+		values[i] = c.next().text
+	case c.accept("RENAME", "TO"):
+		to, err := c.name()
+		if err != nil {
+			return err
+		}
+
+		delete(s.enums, name)
+		s.enums[to] = values
+		s.renameType(name, to)
+
+		return nil
+	}
+	s.enums[name] = values
+
+	return nil
+}
+
+// renameType updates the columns of type from
+func (s *Schema) renameType(from string, to string) {
+	for t := range s.tables {
+		for i := range s.tables[t].Attributes {
+			if string(s.tables[t].Attributes[i].Type) == from {
+				s.tables[t].Attributes[i].Type = resources.AttributeType(to)
+			}
+		}
+	}
+}
+
+// dropType drops enum types, other types are not modelled so dropping them is ignored
+func (s *Schema) dropType(c *cursor, line int) error {
+	c.accept("IF", "EXISTS")
+
+	for {
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		delete(s.enums, name)
+
+		if !c.accept(",") {
+			return nil
+		}
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// index returns the table holding the index named name and its position
+func (s *Schema) index(name string) (*resources.CreateTable, int) {
+	for t := range s.tables {
+		for i, index := range s.tables[t].Indexes {
+			if index.Name == name {
+				return &s.tables[t], i
+			}
+		}
+	}
+
+	return nil, -1
+}
+
+func findAttribute(table *resources.CreateTable, name string) (int, bool) {
+	for i, attr := range table.Attributes {
+		if attr.Name == name {
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+// dropIndexesOn removes the indexes using column, as postgres does when a column is dropped
+func dropIndexesOn(table *resources.CreateTable, column string) {
+	kept := make(resources.Indexes, 0, len(table.Indexes))
+	for _, index := range table.Indexes {
+		if !index.HasAttribute(column) {
+			kept = append(kept, index)
+		}
+	}
+	table.Indexes = kept
+}
+
+// isTableConstraint reports whether a CREATE TABLE element starting with t is
+// a constraint rather than a column
+func isTableConstraint(t token) bool {
+	for _, word := range []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "EXCLUDE", "LIKE"} {
+		if t.is(word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// columnConstraints are the keywords that end the type of a column definition
+var columnConstraints = []string{
+	"NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "REFERENCES",
+	"CHECK", "CONSTRAINT", "COLLATE", "GENERATED",
+}
+
+func isColumnConstraint(t token) bool {
+	for _, word := range columnConstraints {
+		if t.is(word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseColumn reads a column definition of table (i.e. "id UUID NOT NULL")
+func parseColumn(table string, element []token) (column, error) {
+	if len(element) < 2 || element[0].kind != identToken {
+		return column{}, fmt.Errorf("line %d: invalid column definition %q", element[0].line, joinTokens(element))
+	}
+
+	c := &cursor{tokens: element[1:]}
+	typ := make([]token, 0)
+	for depth := 0; !c.done(); {
+		t := c.peek()
+		if depth == 0 && isColumnConstraint(t) {
+			break
+		}
+
+		if t.is("(") {
+			depth++
+		} else if t.is(")") {
+			depth--
+		}
+		typ = append(typ, c.next())
+	}
+
+	col := column{
+		attr: resources.Attribute{
+			Name:     element[0].text,
+			Type:     attributeType(joinTokens(typ)),
+			Nullable: true,
+		},
+	}
+	attr := &col.attr
+
+	constraint := ""
+	for !c.done() {
+		switch {
+		case c.accept("NOT", "NULL"):
+			attr.Nullable = false
+		case c.accept("PRIMARY", "KEY"):
+			attr.Nullable = false
+			col.primaryKey = true
+		case c.accept("DEFAULT"):
+			attr.Default = joinTokens(c.expression())
+		case isIdentity(c):
+			identity, err := acceptIdentity(c)
+			if err != nil {
+				return col, err
+			}
+			attr.Identity, attr.Nullable = identity, false
+		case c.peekAt(3).is("(") && c.accept("GENERATED", "ALWAYS", "AS"):
+			expression := c.expression()
+			if len(expression) < 2 || !expression[len(expression)-1].is(")") {
+				return col, fmt.Errorf("line %d: invalid generated column %q", element[0].line, element[0].text)
+			}
+			attr.Generated = joinTokens(expression[1 : len(expression)-1])
+			c.accept("STORED")
+		case c.accept("REFERENCES"):
+			ref, err := reference(c)
+			if err != nil {
+				return col, err
+			}
+			attr.References = ref
+		case c.accept("CONSTRAINT"):
+			constraint = c.next().text
+			continue
+		case c.accept("UNIQUE"):
+			if constraint == "" {
+				constraint = fmt.Sprintf("%s_%s_key", table, attr.Name)
+			}
+			col.uniques = append(col.uniques, resources.UniqueConstraint{Name: constraint, Columns: []string{attr.Name}})
+		case c.accept("CHECK"):
+			expression, err := checkExpression(c)
+			if err != nil {
+				return col, err
+			}
+
+			// unnamed checks are named once the column is in its table
+			col.checks = append(col.checks, resources.CheckConstraint{Name: constraint, Expression: expression})
+		default:
+			c.next()
+		}
+		constraint = ""
+	}
+
+	return col, nil
+}
+
+// isIdentity returns whether a GENERATED ... AS IDENTITY clause is at the cursor
+func isIdentity(c *cursor) bool {
+	return c.peek().is("GENERATED") && (c.peekAt(3).is("IDENTITY") || c.peekAt(4).is("IDENTITY"))
+}
+
+// acceptIdentity reads a GENERATED ... AS IDENTITY clause and its sequence
+// options, returning its kind (i.e. BY DEFAULT)
+func acceptIdentity(c *cursor) (string, error) {
+	kind := resources.IdentityAlways
+	if !c.accept("GENERATED", "ALWAYS", "AS", "IDENTITY") {
+		if !c.accept("GENERATED", "BY", "DEFAULT", "AS", "IDENTITY") {
+			return "", fmt.Errorf("line %d: invalid identity column", c.peek().line)
+		}
+		kind = resources.IdentityByDefault
+	}
+
+	if c.peek().is("(") {
+		if _, err := c.group(); err != nil {
+			return "", err
+		}
+	}
+
+	return kind, nil
+}
+
+// attributeType maps a SQL column type back to the goils AttributeType that generates it
+func attributeType(sqlType string) resources.AttributeType {
+	return resources.NormalizeType(sqlType)
+}
@@ -0,0 +1,310 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"weavelab.xyz/goils/resources"
+)
+
+func TestParseSQL(t *testing.T) {
+	Convey("ParseSQL builds the tables of a migration", t, func() {
+		f, err := os.Open("testdata/create_setting.sql")
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		schema, err := ParseSQL(f)
+		So(err, ShouldBeNil)
+
+		got, ok := schema.Table("setting")
+		So(ok, ShouldBeTrue)
+		So(got, ShouldResemble, resources.CreateTable{
+			TableName: "setting",
+			Attributes: resources.Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID"},
+				{Name: "name", Type: "string"},
+				{Name: "price", Type: "numeric(12,2)", Nullable: true},
+				{Name: "note", Type: "text", Nullable: true, Default: "'n/a'"},
+				{Name: "created_at", Type: "timestamptz", Default: "now()"},
+			},
+			Indexes: resources.Indexes{
+				{
+					Name:    "idx_setting_location_id",
+					Type:    "BTREE",
+					Columns: []string{"locationid", "id"},
+				},
+				{
+					Name:    "idx_setting_name",
+					Type:    "BTREE",
+					Columns: []string{"name"},
+				},
+			},
+			PrimaryKey: []string{"id"},
+			UniqueConstraints: []resources.UniqueConstraint{
+				{Name: "setting_name_key", Columns: []string{"name"}},
+			},
+			Owner: "schedule",
+		})
+	})
+
+	Convey("ParseSQL reads back what GenerateMigration generates", t, func() {
+		table := resources.CreateTable{
+			TableName: "sms",
+			Attributes: resources.Attributes{
+				{Name: "id", Type: "UUID", Default: "gen_random_uuid()"},
+				{Name: "locationid", Type: "UUID", References: &resources.Reference{Table: "location", OnDelete: "CASCADE"}},
+				{Name: "text", Type: "string", Nullable: true},
+				{Name: "auto", Type: "boolean", Default: "false"},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent", "didn't send"}, Default: "'queued'"},
+				{Name: "length", Type: "integer", Generated: "char_length(text)"},
+			},
+			Indexes: resources.Indexes{
+				{
+					Name:    "idx_sms_location_id",
+					Type:    "BTREE",
+					Columns: []string{"locationid", "id"},
+				},
+			},
+			PrimaryKey: []string{"locationid", "id"},
+			UniqueConstraints: []resources.UniqueConstraint{
+				{Name: "sms_location_text_key", Columns: []string{"locationid", "text"}},
+			},
+			CheckConstraints: []resources.CheckConstraint{
+				{Name: "sms_text_check", Expression: "char_length(text) > 0"},
+			},
+			Owner: "schedule",
+		}
+
+		generated := resources.GenerateMigration(resources.Resource{CreateTable: table})
+		So(generated.AnyErrors(), ShouldBeFalse)
+
+		schema, err := ParseSQL(strings.NewReader(generated[0].Output))
+		So(err, ShouldBeNil)
+		So(schema.Tables(), ShouldResemble, []resources.CreateTable{table})
+	})
+}
+
+func TestSchema_defaults(t *testing.T) {
+	Convey("defaults and generated columns follow ALTER COLUMN", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TABLE sms (
+	id UUID NOT NULL DEFAULT gen_random_uuid(),
+	sent boolean DEFAULT NULL,
+	retries integer NOT NULL DEFAULT 0,
+	total integer GENERATED ALWAYS AS (retries + 1) STORED,
+	seq bigint GENERATED ALWAYS AS IDENTITY,
+	position integer GENERATED BY DEFAULT AS IDENTITY (START WITH 10 INCREMENT BY 10),
+	attempt integer
+);
+ALTER TABLE sms ALTER COLUMN sent SET DEFAULT false;
+ALTER TABLE sms ALTER COLUMN retries DROP DEFAULT;
+ALTER TABLE sms ALTER COLUMN position SET GENERATED ALWAYS;
+ALTER TABLE sms ALTER COLUMN attempt ADD GENERATED BY DEFAULT AS IDENTITY;
+`))
+		So(err, ShouldBeNil)
+
+		got, _ := schema.Table("sms")
+		So(got.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID", Default: "gen_random_uuid()"},
+			{Name: "sent", Type: "boolean", Nullable: true, Default: "false"},
+			{Name: "retries", Type: "integer"},
+			{Name: "total", Type: "integer", Nullable: true, Generated: "retries+1"},
+			{Name: "seq", Type: "bigint", Identity: "ALWAYS"},
+			{Name: "position", Type: "integer", Identity: "ALWAYS"},
+			{Name: "attempt", Type: "integer", Identity: "BY DEFAULT"},
+		})
+	})
+}
+
+func TestSchema_constraints(t *testing.T) {
+	Convey("unique and check constraints follow ALTER TABLE", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TABLE sms (
+	id UUID NOT NULL,
+	location_id UUID NOT NULL,
+	phone text UNIQUE,
+	text text CONSTRAINT sms_text_length CHECK (char_length(text) < 160),
+	sent boolean,
+	UNIQUE (location_id, id),
+	CHECK (sent OR text IS NOT NULL),
+	PRIMARY KEY (id)
+);
+ALTER TABLE sms ADD CONSTRAINT sms_sent_key UNIQUE (sent), ADD CHECK (id IS NOT NULL), ADD CHECK (sent OR phone IS NULL);
+ALTER TABLE sms DROP CONSTRAINT sms_check, DROP CONSTRAINT IF EXISTS sms_pkey;
+ALTER TABLE sms RENAME CONSTRAINT sms_phone_key TO sms_phone_unique;
+ALTER TABLE sms RENAME COLUMN location_id TO locationid;
+ALTER TABLE sms DROP COLUMN sent;
+`))
+		So(err, ShouldBeNil)
+
+		got, _ := schema.Table("sms")
+		So(got.UniqueConstraints, ShouldResemble, []resources.UniqueConstraint{
+			{Name: "sms_phone_unique", Columns: []string{"phone"}},
+			{Name: "sms_location_id_id_key", Columns: []string{"locationid", "id"}},
+		})
+		So(got.CheckConstraints, ShouldResemble, []resources.CheckConstraint{
+			{Name: "sms_text_length", Expression: "char_length(text) < 160"},
+			{Name: "sms_id_check", Expression: "id IS NOT NULL"},
+		})
+		So(got.PrimaryKey, ShouldBeNil)
+	})
+}
+
+func TestSchema_ifExists(t *testing.T) {
+	Convey("ALTER IF EXISTS of a missing table or index is skipped", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TABLE sms (id UUID);
+ALTER TABLE IF EXISTS setting ADD COLUMN name text;
+ALTER INDEX IF EXISTS idx_setting_name RENAME TO idx_setting_key;
+ALTER TABLE IF EXISTS sms ADD COLUMN text text;
+`))
+		So(err, ShouldBeNil)
+
+		So(schema.Tables(), ShouldHaveLength, 1)
+		got, _ := schema.Table("sms")
+		So(got.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID", Nullable: true},
+			{Name: "text", Type: "text", Nullable: true},
+		})
+	})
+}
+
+func TestSchema_primaryKey(t *testing.T) {
+	Convey("the primary key follows ALTER TABLE", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TABLE sms (id UUID PRIMARY KEY, location_id UUID, text text);
+CREATE TABLE setting (location_id UUID, name text, value text);
+ALTER TABLE setting ADD PRIMARY KEY (location_id, name);
+ALTER TABLE setting RENAME COLUMN location_id TO locationid;
+ALTER TABLE sms DROP COLUMN id;
+`))
+		So(err, ShouldBeNil)
+
+		sms, _ := schema.Table("sms")
+		So(sms.PrimaryKey, ShouldBeNil)
+
+		setting, _ := schema.Table("setting")
+		So(setting.PrimaryKey, ShouldResemble, []string{"locationid", "name"})
+		So(setting.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "locationid", Type: "UUID"},
+			{Name: "name", Type: "text"},
+			{Name: "value", Type: "text", Nullable: true},
+		})
+	})
+}
+
+func TestSchema_references(t *testing.T) {
+	Convey("foreign keys follow ALTER TABLE", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TABLE location (id UUID PRIMARY KEY, code text UNIQUE);
+CREATE TABLE sms (
+	id UUID PRIMARY KEY,
+	locationid UUID NOT NULL REFERENCES location ON DELETE CASCADE,
+	code text,
+	parent_id UUID,
+	FOREIGN KEY (code) REFERENCES public.location (code) MATCH FULL ON UPDATE SET NULL
+);
+ALTER TABLE sms ADD CONSTRAINT sms_parent_fk FOREIGN KEY (parent_id) REFERENCES sms (id) ON DELETE NO ACTION;
+ALTER TABLE sms DROP CONSTRAINT sms_code_fkey;
+`))
+		So(err, ShouldBeNil)
+
+		got, _ := schema.Table("sms")
+		So(got.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "locationid", Type: "UUID", References: &resources.Reference{Table: "location", OnDelete: "CASCADE"}},
+			{Name: "code", Type: "text", Nullable: true},
+			{Name: "parent_id", Type: "UUID", Nullable: true, References: &resources.Reference{Table: "sms", OnDelete: "NO ACTION"}},
+		})
+	})
+}
+
+func TestSchema_enums(t *testing.T) {
+	Convey("enum columns get the values of their type", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TYPE sms_status AS ENUM ('queued', 'sent');
+CREATE TYPE sms_kind AS ENUM ('text');
+CREATE TABLE sms (id UUID NOT NULL, status sms_status NOT NULL, kind sms_kind);
+ALTER TYPE sms_status ADD VALUE 'failed';
+ALTER TYPE sms_status ADD VALUE IF NOT EXISTS 'sent';
+ALTER TYPE sms_status ADD VALUE 'sending' BEFORE 'sent';
+ALTER TYPE sms_status RENAME VALUE 'queued' TO 'pending';
+ALTER TYPE sms_kind RENAME TO message_kind;
+CREATE TYPE address AS (street text);
+DROP TYPE address;
+`))
+		So(err, ShouldBeNil)
+
+		got, ok := schema.Table("sms")
+		So(ok, ShouldBeTrue)
+		So(got.Attributes, ShouldResemble, resources.Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "status", Type: "sms_status", Values: []string{"pending", "sending", "sent", "failed"}},
+			{Name: "kind", Type: "message_kind", Nullable: true, Values: []string{"text"}},
+		})
+	})
+
+	Convey("dropped enum types lose their values", t, func() {
+		schema, err := ParseSQL(strings.NewReader(`
+CREATE TYPE sms_status AS ENUM ('queued');
+CREATE TABLE sms (status sms_status);
+ALTER TABLE sms ALTER COLUMN status TYPE text;
+DROP TYPE IF EXISTS sms_status, sms_kind;
+`))
+		So(err, ShouldBeNil)
+
+		got, _ := schema.Table("sms")
+		So(got.Attributes, ShouldResemble, resources.Attributes{{Name: "status", Type: "text", Nullable: true}})
+	})
+}
+
+func TestParseSQL_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "index on an unknown table",
+			sql:  "CREATE INDEX idx ON sms (id);",
+			want: `line 1: index "idx" is on unknown table "sms"`,
+		},
+		{
+			name: "index on an expression",
+			sql:  "CREATE TABLE sms (name text);\nCREATE INDEX idx ON sms (lower(name));",
+			want: `line 2: index "idx" is on the expression lower(name), only column indexes are imported`,
+		},
+		{
+			name: "table created twice",
+			sql:  "CREATE TABLE sms (id UUID);\n\nCREATE TABLE sms (id UUID);",
+			want: `line 3: table "sms" already exists`,
+		},
+		{
+			name: "enum created twice",
+			sql:  "CREATE TYPE kind AS ENUM ('a');\nCREATE TYPE kind AS ENUM ('b');",
+			want: `line 2: type "kind" already exists`,
+		},
+		{
+			name: "enum value added before an unknown value",
+			sql:  "CREATE TYPE kind AS ENUM ('a');\nALTER TYPE kind ADD VALUE 'b' BEFORE 'c';",
+			want: `line 2: enum "kind" has no such value`,
+		},
+		{
+			name: "unterminated quote",
+			sql:  "CREATE TABLE sms (\n\"id UUID);",
+			want: "line 2: unterminated \" quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseSQL", t, func() {
+				_, err := ParseSQL(strings.NewReader(tt.sql))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, tt.want)
+			})
+		})
+	}
+}
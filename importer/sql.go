@@ -0,0 +1,408 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	stringToken
+	numberToken
+	punctToken
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	quoted bool
+}
+
+// is reports whether the token is the (case insensitive) keyword or punctuation
+func (t token) is(word string) bool {
+	if t.quoted || t.kind == stringToken {
+		return false
+	}
+
+	return strings.EqualFold(t.text, word)
+}
+
+// statement is a single SQL statement without its terminating semicolon
+type statement struct {
+	tokens []token
+	line   int
+}
+
+func (s statement) String() string {
+	return joinTokens(s.tokens)
+}
+
+// tokenize splits src into SQL tokens, skipping whitespace and comments
+func tokenize(src string, line int) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for ; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			i += 2
+		case r == '\'' || r == '"':
+			start := line
+			text := strings.Builder{}
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("line %d: unterminated %c quote", start, r)
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						text.WriteRune(r)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+
+			if r == '"' {
+				tokens = append(tokens, token{kind: identToken, text: text.String(), line: start, quoted: true})
+			} else {
+				tokens = append(tokens, token{kind: stringToken, text: text.String(), line: start})
+			}
+		case r == '$' && dollarTag(runes[i:]) != "":
+			start := line
+			tag := dollarTag(runes[i:])
+			i += len([]rune(tag))
+
+			end := strings.Index(string(runes[i:]), tag)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated %s quote", start, tag)
+			}
+
+			body := []rune(string(runes[i:])[:end])
+			line += strings.Count(string(body), "\n")
+			i += len(body) + len([]rune(tag))
+			tokens = append(tokens, token{kind: stringToken, text: string(body), line: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(runes[start:i]), line: line})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: numberToken, text: string(runes[start:i]), line: line})
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, token{kind: punctToken, text: "::", line: line})
+			i += 2
+		default:
+			tokens = append(tokens, token{kind: punctToken, text: string(r), line: line})
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// dollarTag returns the $tag$ opening a dollar quoted string at the start of runes
+func dollarTag(runes []rune) string {
+	for i := 1; i < len(runes); i++ {
+		switch {
+		case runes[i] == '$':
+			return string(runes[:i+1])
+		case !(unicode.IsLetter(runes[i]) || runes[i] == '_' || (i > 1 && unicode.IsDigit(runes[i]))):
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// splitStatements splits tokens on semicolons
+func splitStatements(tokens []token) []statement {
+	statements := make([]statement, 0)
+	current := make([]token, 0)
+
+	for _, t := range tokens {
+		if t.kind == punctToken && t.text == ";" {
+			if len(current) > 0 {
+				statements = append(statements, statement{tokens: current, line: current[0].line})
+			}
+			current = make([]token, 0)
+			continue
+		}
+
+		current = append(current, t)
+	}
+
+	if len(current) > 0 {
+		statements = append(statements, statement{tokens: current, line: current[0].line})
+	}
+
+	return statements
+}
+
+// parseStatements reads every statement of the SQL script src, line is the
+// line src starts at in its file
+func parseStatements(src string, line int) ([]statement, error) {
+	tokens, err := tokenize(src, line)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitStatements(tokens), nil
+}
+
+// ParseSQL reads a SQL script of CREATE TABLE, CREATE INDEX and ALTER TABLE
+// statements and returns the schema they build. For goose migrations only
+// the Up section is read
+func ParseSQL(r io.Reader) (*Schema, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	src := string(data)
+	if strings.Contains(src, gooseUp) {
+		src = upSection(src)
+	}
+
+	statements, err := parseStatements(src, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := NewSchema()
+	for _, stmt := range statements {
+		if err := schema.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+// cursor walks the tokens of a statement
+type cursor struct {
+	tokens []token
+	pos    int
+}
+
+func (c *cursor) done() bool {
+	return c.pos >= len(c.tokens)
+}
+
+func (c *cursor) peek() token {
+	return c.peekAt(0)
+}
+
+// peekAt returns the token n positions ahead without consuming it
+func (c *cursor) peekAt(n int) token {
+	if c.pos+n >= len(c.tokens) {
+		return token{kind: punctToken}
+	}
+
+	return c.tokens[c.pos+n]
+}
+
+func (c *cursor) next() token {
+	t := c.peek()
+	c.pos++
+
+	return t
+}
+
+// accept consumes words when the next tokens match all of them
+func (c *cursor) accept(words ...string) bool {
+	if c.pos+len(words) > len(c.tokens) {
+		return false
+	}
+
+	for i, word := range words {
+		if !c.tokens[c.pos+i].is(word) {
+			return false
+		}
+	}
+	c.pos += len(words)
+
+	return true
+}
+
+// name reads a possibly schema qualified name and returns its last part
+func (c *cursor) name() (string, error) {
+	t := c.next()
+	if t.kind != identToken {
+		return "", fmt.Errorf("line %d: expected a name, got %q", t.line, t.text)
+	}
+
+	for c.peek().is(".") {
+		c.next()
+		t = c.next()
+		if t.kind != identToken {
+			return "", fmt.Errorf("line %d: expected a name, got %q", t.line, t.text)
+		}
+	}
+
+	return t.text, nil
+}
+
+// group reads a parenthesised list, returning the tokens of each comma separated element
+func (c *cursor) group() ([][]token, error) {
+	open := c.next()
+	if !open.is("(") {
+		return nil, fmt.Errorf("line %d: expected \"(\", got %q", open.line, open.text)
+	}
+
+	elements := make([][]token, 0)
+	current := make([]token, 0)
+	depth := 0
+
+	for !c.done() {
+		t := c.next()
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")") && depth == 0:
+			if len(current) > 0 {
+				elements = append(elements, current)
+			}
+			return elements, nil
+		case t.is(")"):
+			depth--
+		case t.is(",") && depth == 0:
+			elements = append(elements, current)
+			current = make([]token, 0)
+			continue
+		}
+
+		current = append(current, t)
+	}
+
+	return nil, fmt.Errorf("line %d: missing \")\"", open.line)
+}
+
+// element reads tokens up to the next comma outside of parentheses
+func (c *cursor) element() []token {
+	tokens := make([]token, 0)
+	for depth := 0; !c.done(); {
+		t := c.peek()
+		if depth == 0 && t.is(",") {
+			break
+		}
+
+		if t.is("(") {
+			depth++
+		} else if t.is(")") {
+			depth--
+		}
+		tokens = append(tokens, c.next())
+	}
+
+	return tokens
+}
+
+// expression reads the tokens of a column constraint's expression, up to the
+// next constraint keyword outside of parentheses
+func (c *cursor) expression() []token {
+	tokens := make([]token, 0)
+	for depth := 0; !c.done(); {
+		t := c.peek()
+		if depth == 0 && (isColumnConstraint(t) || t.is("STORED")) && len(tokens) > 0 {
+			break
+		}
+
+		if t.is("(") {
+			depth++
+		} else if t.is(")") {
+			depth--
+		}
+		tokens = append(tokens, c.next())
+	}
+
+	return tokens
+}
+
+// joinTokens renders tokens back into SQL text
+func joinTokens(tokens []token) string {
+	b := strings.Builder{}
+	for i, t := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], t) {
+			b.WriteString(" ")
+		}
+
+		switch {
+		case t.kind == stringToken:
+			b.WriteString("'" + strings.ReplaceAll(t.text, "'", "''") + "'")
+		case t.quoted:
+			b.WriteString(`"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`)
+		default:
+			b.WriteString(t.text)
+		}
+	}
+
+	return b.String()
+}
+
+func needsSpace(prev, t token) bool {
+	if isComparison(t) || isComparison(prev) {
+		return !(isComparison(prev) && isComparison(t)) // i.e. <=
+	}
+
+	return t.kind != punctToken && prev.kind != punctToken
+}
+
+func isComparison(t token) bool {
+	return t.kind == punctToken && (t.text == "=" || t.text == "<" || t.text == ">" || t.text == "!")
+}
+
+// LoadSQL reads the SQL script at path and returns the schema it builds
+func LoadSQL(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	schema, err := ParseSQL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return schema, nil
+}
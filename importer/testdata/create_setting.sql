@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.setting
+(
+	id UUID NOT NULL PRIMARY KEY,
+	locationid uuid NOT NULL,
+	name varchar(120) NOT NULL,
+	price numeric(12, 2),
+	"note" text DEFAULT 'n/a', -- free form
+	created_at timestamp with time zone NOT NULL DEFAULT now(),
+	CONSTRAINT setting_name_key UNIQUE (name)
+)
+WITH
+(
+	OIDS=FALSE
+);
+/* indexes */
+CREATE INDEX IF NOT EXISTS idx_setting_location_id
+	ON setting
+	USING btree
+(locationid, id);
+CREATE UNIQUE INDEX idx_setting_name ON setting (name);
+INSERT INTO setting (id) VALUES ('00000000-0000-0000-0000-000000000000');
+ALTER TABLE setting
+	OWNER TO "schedule";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- +goose StatementEnd
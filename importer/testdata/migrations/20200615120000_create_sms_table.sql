@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sms
+(
+	id UUID NOT NULL,
+	locationid UUID NOT NULL,
+	text varchar(120) NOT NULL,
+	sent boolean
+)
+WITH
+(
+	OIDS=FALSE
+);
+CREATE INDEX IF NOT EXISTS idx_sms_location_id
+	ON sms
+	USING BTREE
+(locationid, id);
+ALTER TABLE sms
+	OWNER TO "schedule";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sms;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE sms ADD COLUMN created_at timestamptz NOT NULL DEFAULT now(),
+	DROP COLUMN sent,
+	ALTER COLUMN text TYPE text USING text::text;
+ALTER TABLE sms RENAME COLUMN locationid TO location_id;
+ALTER INDEX idx_sms_location_id RENAME TO idx_sms_location;
+CREATE TABLE tmp (id UUID);
+
+-- +goose StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+	NEW.created_at = now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE sms DROP COLUMN created_at;
+DROP TABLE tmp;
@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE TABLE setting (id UUID NOT NULL, name text);
+DROP INDEX IF EXISTS idx_missing;
+
+-- +goose Down
+DROP TABLE setting;
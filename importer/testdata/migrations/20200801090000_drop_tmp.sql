@@ -0,0 +1,6 @@
+-- +goose Up
+DROP TABLE IF EXISTS tmp, missing;
+ALTER TABLE sms ALTER COLUMN text DROP NOT NULL;
+
+-- +goose Down
+CREATE TABLE tmp (id UUID);
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Location struct {
+	ID uuid.UUID
+}
+
+type Setting struct {
+	ID         uuid.UUID       `db:"id" goils:"index=idx_setting_location_id"`
+	LocationID uuid.UUID       `db:"locationid" goils:"index=idx_setting_location_id"`
+	Name       string          `db:"name,omitempty"`
+	Note       *string         `goils:"type=text"`
+	Priority   sql.NullInt32   `db:"priority"`
+	Tags       []string        `db:"tags"`
+	Options    json.RawMessage `db:"options"`
+	CreatedAt  time.Time       `db:"created_at" goils:"index=idx_setting_created_at"`
+	Ignored    string          `db:"-"`
+	internal   string
+}
+
+type Broken struct {
+	Lookup map[string]int
+}
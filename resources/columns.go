@@ -0,0 +1,339 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rename is a column, constraint or index renamed from From to To
+type Rename struct {
+	From string
+	To   string
+}
+
+// ColumnMigration is a targeted change of the columns of a resource (i.e.
+// adding a status column), Resource being the resource once changed
+type ColumnMigration struct {
+	Name     string // describes the change in the file name (i.e. add_status_to_sms)
+	Resource Resource
+	Alter    AlterTable
+}
+
+// AddColumns adds attrs to the resource
+func AddColumns(resource Resource, attrs ...Attribute) (ColumnMigration, error) {
+	changed := resource.clone()
+	names := make([]string, len(attrs))
+	for i, attr := range attrs {
+		if changed.Attributes.Has(attr.Name) {
+			return ColumnMigration{}, fmt.Errorf("column %q of %q already exists", attr.Name, resource.TableName)
+		}
+
+		changed.Attributes = append(changed.Attributes, attr)
+		names[i] = attr.Name
+	}
+
+	return newColumnMigration(fmt.Sprintf("add_%s_to_%s", strings.Join(names, "_"), resource.TableName), resource, changed)
+}
+
+// DropColumns drops the columns named names and the indexes, unique and check
+// constraints on them
+func DropColumns(resource Resource, names ...string) (ColumnMigration, error) {
+	changed := resource.clone()
+	for _, name := range names {
+		i := indexOfAttribute(changed.Attributes, name)
+		switch {
+		case i < 0:
+			return ColumnMigration{}, fmt.Errorf("column %q of %q does not exist", name, resource.TableName)
+		case indexOf(changed.PrimaryKey, name) >= 0:
+			return ColumnMigration{}, fmt.Errorf("column %q of %q is part of the primary key", name, resource.TableName)
+		}
+
+		changed.Attributes = append(changed.Attributes[:i:i], changed.Attributes[i+1:]...)
+
+		indexes := make(Indexes, 0, len(changed.Indexes))
+		for _, index := range changed.Indexes {
+			if !index.HasAttribute(name) {
+				indexes = append(indexes, index)
+			}
+		}
+		changed.Indexes = indexes
+
+		uniques := make([]UniqueConstraint, 0, len(changed.UniqueConstraints))
+		for _, unique := range changed.UniqueConstraints {
+			if indexOf(unique.Columns, name) < 0 {
+				uniques = append(uniques, unique)
+			}
+		}
+		changed.UniqueConstraints = uniques
+
+		checks := make([]CheckConstraint, 0, len(changed.CheckConstraints))
+		for _, check := range changed.CheckConstraints {
+			if indexOf(expressionColumns(check.Expression), name) < 0 {
+				checks = append(checks, check)
+			}
+		}
+		changed.CheckConstraints = checks
+	}
+
+	return newColumnMigration(fmt.Sprintf("drop_%s_from_%s", strings.Join(names, "_"), resource.TableName), resource, changed)
+}
+
+// RenameColumn renames the column from to to, in the indexes, constraints and
+// generated columns using it too. Its foreign key and the index supporting it
+// keep postgres' naming (i.e. sms_locationid_fkey becomes sms_location_id_fkey)
+func RenameColumn(resource Resource, from string, to string) (ColumnMigration, error) {
+	i := indexOfAttribute(resource.Attributes, from)
+	switch {
+	case i < 0:
+		return ColumnMigration{}, fmt.Errorf("column %q of %q does not exist", from, resource.TableName)
+	case resource.Attributes.Has(to):
+		return ColumnMigration{}, fmt.Errorf("column %q of %q already exists", to, resource.TableName)
+	}
+
+	changed := resource.clone()
+	changed.Attributes[i].Name = to
+	rename := func(columns []string) {
+		if j := indexOf(columns, from); j >= 0 {
+			columns[j] = to
+		}
+	}
+	for _, index := range changed.Indexes {
+		rename(index.Columns)
+	}
+	for _, unique := range changed.UniqueConstraints {
+		rename(unique.Columns)
+	}
+	rename(changed.PrimaryKey)
+	for j, check := range changed.CheckConstraints {
+		changed.CheckConstraints[j].Expression = renameIdentifier(check.Expression, from, to)
+	}
+	for j, attr := range changed.Attributes {
+		changed.Attributes[j].Generated = renameIdentifier(attr.Generated, from, to)
+	}
+
+	up := TableChanges{TableName: resource.TableName, RenameColumns: []Rename{{from, to}}}
+	if resource.Attributes[i].References != nil {
+		key := Rename{
+			From: fmt.Sprintf("%s_%s_fkey", resource.TableName, from),
+			To:   fmt.Sprintf("%s_%s_fkey", resource.TableName, to),
+		}
+		up.RenameConstraints = append(up.RenameConstraints, key)
+
+		index := Rename{
+			From: fmt.Sprintf("idx_%s_%s", resource.TableName, from),
+			To:   fmt.Sprintf("idx_%s_%s", resource.TableName, to),
+		}
+		if hasIndexNamed(resource.AllIndexes(), index.From) && !hasIndexNamed(resource.Indexes, index.From) {
+			up.RenameIndexes = append(up.RenameIndexes, index)
+		}
+	}
+
+	return ColumnMigration{
+		Name:     fmt.Sprintf("rename_%s_to_%s_in_%s", from, to, resource.TableName),
+		Resource: changed,
+		Alter: AlterTable{
+			TableName: resource.TableName,
+			Up:        up,
+			Down:      up.inverse(),
+		},
+	}, nil
+}
+
+// RenameReferenced points the foreign keys of resource referencing the column
+// from of table at to, the migration renaming it is RenameColumn's
+func RenameReferenced(resource Resource, table string, from string, to string) Resource {
+	changed := resource.clone()
+	for i, attr := range changed.Attributes {
+		if attr.References == nil || attr.References.Table != table || attr.References.ReferencedColumn() != from {
+			continue
+		}
+
+		reference := *attr.References
+		reference.Column = to
+		changed.Attributes[i].References = &reference
+	}
+
+	return changed
+}
+
+// renameIdentifier replaces the column from of an SQL expression by to
+func renameIdentifier(expression string, from string, to string) string {
+	return mapColumns(expression, func(column string) string {
+		if column == from {
+			return to
+		}
+
+		return column
+	})
+}
+
+// expressionKeywords are the keywords of expressions that are not reserved
+// words (i.e. BETWEEN or AT TIME ZONE)
+var expressionKeywords = map[string]bool{
+	"at": true, "between": true, "character": true, "date": true, "double": true,
+	"escape": true, "interval": true, "nulls": true, "precision": true, "time": true,
+	"timestamp": true, "unknown": true, "varying": true, "without": true, "zone": true,
+}
+
+// expressionColumns returns the columns an SQL expression uses, once each
+func expressionColumns(expression string) []string {
+	columns := make([]string, 0)
+	mapColumns(expression, func(column string) string {
+		word := strings.ToLower(column)
+		if !reservedWords[word] && !expressionKeywords[word] && indexOf(columns, column) < 0 {
+			columns = append(columns, column)
+		}
+
+		return column
+	})
+
+	return columns
+}
+
+// mapColumns replaces the columns of an SQL expression by what column returns
+// for them. String literals, numbers, parameters, function names, casts (i.e.
+// ::text) and the types of typed literals (i.e. interval '1 day') are left as
+// they are
+func mapColumns(expression string, column func(name string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expression[i+1:], c)
+			if end < 0 {
+				b.WriteString(expression[i:])
+				return b.String()
+			}
+			end += i + 2
+
+			if c == '"' {
+				b.WriteString(`"` + column(expression[i+1:end-1]) + `"`)
+			} else {
+				b.WriteString(expression[i:end])
+			}
+			i = end
+		case isIdentifierByte(c):
+			end := i
+			for end < len(expression) && isIdentifierByte(expression[end]) {
+				end++
+			}
+
+			word := expression[i:end]
+			next := strings.TrimLeft(expression[end:], " ")
+			literal := c == '$' || c >= '0' && c <= '9'
+			cast := i > 0 && expression[i-1] == ':'
+			call := strings.HasPrefix(next, "(")
+			typed := strings.HasPrefix(next, "'")
+			if !literal && !cast && !call && !typed {
+				word = column(word)
+			}
+			b.WriteString(word)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ChangeType changes the type of the column named name to typ, using converts
+// the values (i.e. text::integer) and is left to postgres when empty. Down
+// casts the values back to the previous type
+func ChangeType(resource Resource, name string, typ AttributeType, using string) (ColumnMigration, error) {
+	i := indexOfAttribute(resource.Attributes, name)
+	if i < 0 {
+		return ColumnMigration{}, fmt.Errorf("column %q of %q does not exist", name, resource.TableName)
+	}
+	previous := resource.Attributes[i]
+
+	changed := resource.clone()
+	changed.Attributes[i].Type = typ
+	changed.Attributes[i].Values = nil
+
+	migration, err := newColumnMigration(fmt.Sprintf("change_%s_type_in_%s", name, resource.TableName), resource, changed)
+	if err != nil {
+		return migration, err
+	}
+
+	if using != "" {
+		withUsing(migration.Alter.Up.AlterColumns, name, using)
+		withUsing(migration.Alter.Down.AlterColumns, name, fmt.Sprintf("%s::%s", name, previous.Type.ToSQL()))
+	}
+
+	return migration, nil
+}
+
+// newColumnMigration returns the migration named name changing resource into changed
+func newColumnMigration(name string, resource Resource, changed Resource) (ColumnMigration, error) {
+	alter, err := Diff(resource, changed)
+	if err != nil {
+		return ColumnMigration{}, err
+	}
+
+	if alter.Up.Empty() {
+		return ColumnMigration{}, fmt.Errorf("table %q has no changes", resource.TableName)
+	}
+
+	return ColumnMigration{Name: name, Resource: changed, Alter: alter}, nil
+}
+
+// withUsing adds the USING clause to the type alteration of the column name
+func withUsing(alterations []string, name string, using string) {
+	for i, alteration := range alterations {
+		if strings.HasPrefix(alteration, name+" TYPE ") {
+			alterations[i] += " USING " + using
+		}
+	}
+}
+
+// inverse returns the renames of the changes, reverted
+func (t TableChanges) inverse() TableChanges {
+	revert := func(renames []Rename) []Rename {
+		reverted := make([]Rename, len(renames))
+		for i, rename := range renames {
+			reverted[len(renames)-1-i] = Rename{From: rename.To, To: rename.From}
+		}
+
+		return reverted
+	}
+
+	return TableChanges{
+		TableName:         t.TableName,
+		RenameColumns:     revert(t.RenameColumns),
+		RenameConstraints: revert(t.RenameConstraints),
+		RenameIndexes:     revert(t.RenameIndexes),
+	}
+}
+
+func hasIndexNamed(indexes Indexes, name string) bool {
+	return indexes.Any(func(index Index) bool {
+		return index.Name == name
+	})
+}
+
+// clone returns a copy of the resource whose table can be changed without
+// changing the resource's
+func (r Resource) clone() Resource {
+	c := r
+	c.Attributes = append(Attributes(nil), r.Attributes...)
+	c.PrimaryKey = append([]string(nil), r.PrimaryKey...)
+	c.CheckConstraints = append([]CheckConstraint(nil), r.CheckConstraints...)
+
+	c.Indexes = append(Indexes(nil), r.Indexes...)
+	for i, index := range c.Indexes {
+		c.Indexes[i].Columns = append([]string(nil), index.Columns...)
+	}
+
+	c.UniqueConstraints = append([]UniqueConstraint(nil), r.UniqueConstraints...)
+	for i, unique := range c.UniqueConstraints {
+		c.UniqueConstraints[i].Columns = append([]string(nil), unique.Columns...)
+	}
+
+	return c
+}
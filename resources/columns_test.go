@@ -0,0 +1,147 @@
+package resources
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestColumnMigrations(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID", References: &Reference{Table: "location"}},
+				{Name: "text", Type: "text"},
+			},
+			Indexes:           Indexes{{Name: "idx_sms_text", Type: "BTREE", Columns: []string{"text", "id"}}},
+			UniqueConstraints: []UniqueConstraint{{Name: "sms_text_key", Columns: []string{"locationid", "text"}}},
+			CheckConstraints:  []CheckConstraint{{Name: "sms_locationid_check", Expression: "locationid IS NOT NULL OR text <> 'locationid'"}},
+		},
+	}
+
+	Convey("AddColumns adds the columns and drops them in Down", t, func() {
+		migration, err := AddColumns(resource, Attribute{Name: "status", Type: "string"}, Attribute{Name: "sent_at", Type: "timestamptz", Nullable: true})
+		So(err, ShouldBeNil)
+		So(migration.Resource.Attributes[3:], ShouldResemble, Attributes{
+			{Name: "status", Type: "string"},
+			{Name: "sent_at", Type: "timestamptz", Nullable: true},
+		})
+		So(resource.Attributes, ShouldHaveLength, 3)
+
+		generated := GenerateColumnMigration(migration)
+		So(generated[0].FileOut, ShouldEqual, "20200615120000_add_status_sent_at_to_sms.sql")
+		So(generated[0].Output, ShouldEqual, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms ADD COLUMN status varchar(120) NOT NULL;\n"+
+			"ALTER TABLE sms ADD COLUMN sent_at timestamptz;\n"+
+			"-- +goose StatementEnd\n\n"+
+			"-- +goose Down\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms DROP COLUMN status;\n"+
+			"ALTER TABLE sms DROP COLUMN sent_at;\n"+
+			"-- +goose StatementEnd")
+
+		_, err = AddColumns(resource, Attribute{Name: "text", Type: "text"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `column "text" of "sms" already exists`)
+	})
+
+	Convey("DropColumns drops the columns with their indexes and constraints", t, func() {
+		migration, err := DropColumns(resource, "text")
+		So(err, ShouldBeNil)
+		So(migration.Name, ShouldEqual, "drop_text_from_sms")
+		So(migration.Resource.Indexes, ShouldBeEmpty)
+		So(migration.Resource.UniqueConstraints, ShouldBeEmpty)
+		So(migration.Resource.CheckConstraints, ShouldBeEmpty)
+		So(migration.Resource.Validate(), ShouldBeEmpty)
+		So(migration.Alter.Up, ShouldResemble, TableChanges{
+			TableName:       "sms",
+			DropConstraints: []string{"sms_text_key", "sms_locationid_check"},
+			DropIndexes:     []string{"idx_sms_text"},
+			DropColumns:     []string{"text"},
+		})
+		So(migration.Alter.Down, ShouldResemble, TableChanges{
+			TableName:  "sms",
+			AddColumns: []string{"text text NOT NULL"},
+			AddConstraints: []string{
+				"CONSTRAINT sms_text_key UNIQUE (locationid, text)",
+				"CONSTRAINT sms_locationid_check CHECK (locationid IS NOT NULL OR text <> 'locationid')",
+			},
+			CreateIndexes: Indexes{{Name: "idx_sms_text", Type: "BTREE", Columns: []string{"text", "id"}}},
+		})
+
+		keyed := resource
+		keyed.PrimaryKey = []string{"id"}
+		_, err = DropColumns(keyed, "id")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `column "id" of "sms" is part of the primary key`)
+
+		_, err = DropColumns(resource, "status")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `column "status" of "sms" does not exist`)
+	})
+
+	Convey("RenameColumn renames the column, its foreign key and the index supporting it", t, func() {
+		migration, err := RenameColumn(resource, "locationid", "location_id")
+		So(err, ShouldBeNil)
+		So(migration.Resource.Attributes[1].Name, ShouldEqual, "location_id")
+		So(migration.Resource.UniqueConstraints[0].Columns, ShouldResemble, []string{"location_id", "text"})
+		So(resource.UniqueConstraints[0].Columns, ShouldResemble, []string{"locationid", "text"})
+		So(migration.Resource.CheckConstraints[0].Expression, ShouldEqual, "location_id IS NOT NULL OR text <> 'locationid'")
+		So(resource.CheckConstraints[0].Expression, ShouldEqual, "locationid IS NOT NULL OR text <> 'locationid'")
+
+		generated := GenerateColumnMigration(migration)
+		So(generated[0].FileOut, ShouldEqual, "20200615120000_rename_locationid_to_location_id_in_sms.sql")
+		So(generated[0].Output, ShouldEqual, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms RENAME COLUMN locationid TO location_id;\n"+
+			"ALTER TABLE sms RENAME CONSTRAINT sms_locationid_fkey TO sms_location_id_fkey;\n"+
+			"ALTER INDEX idx_sms_locationid RENAME TO idx_sms_location_id;\n"+
+			"-- +goose StatementEnd\n\n"+
+			"-- +goose Down\n-- +goose StatementBegin\n"+
+			"ALTER TABLE sms RENAME COLUMN location_id TO locationid;\n"+
+			"ALTER TABLE sms RENAME CONSTRAINT sms_location_id_fkey TO sms_locationid_fkey;\n"+
+			"ALTER INDEX idx_sms_location_id RENAME TO idx_sms_locationid;\n"+
+			"-- +goose StatementEnd")
+
+		_, err = RenameColumn(resource, "text", "id")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `column "id" of "sms" already exists`)
+	})
+
+	Convey("RenameReferenced points the foreign keys at the renamed column", t, func() {
+		referenced := RenameReferenced(resource, "location", "id", "location_id")
+		So(referenced.Attributes[1].References, ShouldResemble, &Reference{Table: "location", Column: "location_id"})
+		So(resource.Attributes[1].References, ShouldResemble, &Reference{Table: "location"})
+
+		So(RenameReferenced(resource, "location", "name", "title").Attributes[1].References, ShouldResemble, &Reference{Table: "location"})
+	})
+
+	Convey("renameIdentifier leaves strings, function names and casts alone", t, func() {
+		So(renameIdentifier(`length(text) > 0 AND text::text <> 'text' AND "text" <> texts`, "text", "body"),
+			ShouldEqual, `length(body) > 0 AND body::text <> 'text' AND "body" <> texts`)
+	})
+
+	Convey("expressionColumns lists the columns but not keywords, literals or types", t, func() {
+		So(expressionColumns(`sent_at > created_at + interval '1 day' AND sent_at AT TIME ZONE 'UTC' BETWEEN created_at AND now() AND text IS NOT NULL AND tries::text <> '0' AND "Code" IN ($1, 2.5)`),
+			ShouldResemble, []string{"sent_at", "created_at", "text", "tries", "Code"})
+	})
+
+	Convey("ChangeType converts the values with using and back in Down", t, func() {
+		migration, err := ChangeType(resource, "text", "varchar(255)", "left(text, 255)")
+		So(err, ShouldBeNil)
+		So(migration.Name, ShouldEqual, "change_text_type_in_sms")
+		So(migration.Alter.Up.AlterColumns, ShouldResemble, []string{"text TYPE varchar(255) USING left(text, 255)"})
+		So(migration.Alter.Down.AlterColumns, ShouldResemble, []string{"text TYPE text USING text::text"})
+
+		migration, err = ChangeType(resource, "text", "varchar(255)", "")
+		So(err, ShouldBeNil)
+		So(migration.Alter.Up.AlterColumns, ShouldResemble, []string{"text TYPE varchar(255)"})
+
+		_, err = ChangeType(resource, "text", "text", "")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `table "sms" has no changes`)
+	})
+}
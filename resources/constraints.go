@@ -0,0 +1,120 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UniqueConstraint is a named UNIQUE constraint on one or more columns
+type UniqueConstraint struct {
+	Name    string   `yaml:"name"`
+	Columns []string `yaml:"columns"`
+}
+
+// CheckConstraint is a named CHECK constraint (i.e. char_length(text) > 0)
+type CheckConstraint struct {
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+}
+
+func (u UniqueConstraint) ToTemplate() string {
+	return fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", u.Name, strings.Join(u.Columns, ", "))
+}
+
+func (c CheckConstraint) ToTemplate() string {
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", c.Name, c.Expression)
+}
+
+// Elements returns the column and constraint definitions of the CREATE TABLE
+func (c CreateTable) Elements() []string {
+	elements := make([]string, 0, len(c.Attributes)+1+len(c.UniqueConstraints)+len(c.CheckConstraints))
+	for _, attr := range c.Attributes {
+		elements = append(elements, attr.ToTemplate())
+	}
+
+	if primaryKey := c.primaryKeyTemplate(); primaryKey != "" {
+		elements = append(elements, primaryKey)
+	}
+
+	for _, key := range c.ForeignKeys() {
+		elements = append(elements, key.ToTemplate())
+	}
+
+	for _, unique := range c.UniqueConstraints {
+		elements = append(elements, unique.ToTemplate())
+	}
+
+	for _, check := range c.CheckConstraints {
+		elements = append(elements, check.ToTemplate())
+	}
+
+	return elements
+}
+
+// ConstraintNames returns the names of the constraints, in the order they are created
+func (c CreateTable) ConstraintNames() []string {
+	names := make([]string, 0, len(c.UniqueConstraints)+len(c.CheckConstraints))
+	for _, key := range c.ForeignKeys() {
+		names = append(names, key.Name)
+	}
+
+	for _, unique := range c.UniqueConstraints {
+		names = append(names, unique.Name)
+	}
+
+	for _, check := range c.CheckConstraints {
+		names = append(names, check.Name)
+	}
+
+	return names
+}
+
+// validateConstraints reports unnamed constraints, names taken by another
+// constraint or index and columns that are not attributes
+func (c CreateTable) validateConstraints(d *Diagnostics, attributes map[string]bool, names map[string]bool) {
+	checkConstraintName := func(field string, name string) {
+		switch {
+		case name == "":
+			d.add(field, "is required")
+		case names[name]:
+			d.add(field, "duplicate constraint or index %q", name)
+		default:
+			checkName(d, field, name)
+		}
+		names[name] = true
+	}
+
+	for i, attr := range c.Attributes {
+		if attr.References != nil {
+			checkConstraintName(fmt.Sprintf("attributes[%d].references", i), fmt.Sprintf("%s_%s_fkey", c.TableName, attr.Name))
+		}
+	}
+
+	for i, unique := range c.UniqueConstraints {
+		field := fmt.Sprintf("unique_constraints[%d]", i)
+		checkConstraintName(field+".name", unique.Name)
+
+		if len(unique.Columns) == 0 {
+			d.add(field+".columns", "at least one column is required")
+		}
+		for j, column := range unique.Columns {
+			if !attributes[column] {
+				d.add(fmt.Sprintf("%s.columns[%d]", field, j), "%q is not an attribute", column)
+			}
+		}
+	}
+
+	for i, check := range c.CheckConstraints {
+		field := fmt.Sprintf("check_constraints[%d]", i)
+		checkConstraintName(field+".name", check.Name)
+
+		if strings.TrimSpace(check.Expression) == "" {
+			d.add(field+".expression", "is required")
+		}
+		for _, column := range expressionColumns(check.Expression) {
+			if !attributes[column] {
+				d.add(field+".expression", "%q is not an attribute", column)
+			}
+		}
+	}
+}
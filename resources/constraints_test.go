@@ -0,0 +1,70 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateTable_constraints(t *testing.T) {
+	resource := Resource{
+		Package: "main",
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID"},
+				{Name: "text", Type: "text"},
+			},
+			UniqueConstraints: []UniqueConstraint{
+				{Name: "sms_location_text_key", Columns: []string{"locationid", "text"}},
+			},
+			CheckConstraints: []CheckConstraint{
+				{Name: "sms_text_check", Expression: "char_length(text) > 0"},
+			},
+		},
+		CrudOptions: []CrudOption{"create"},
+	}
+
+	Convey("the migration creates the constraints and drops them in Down", t, func() {
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldContainSubstring, "\ttext text NOT NULL,\n"+
+			"\tPRIMARY KEY (id),\n"+
+			"\tCONSTRAINT sms_location_text_key UNIQUE (locationid, text),\n"+
+			"\tCONSTRAINT sms_text_check CHECK (char_length(text) > 0)\n)")
+		So(migration, ShouldContainSubstring, "-- +goose StatementBegin\n"+
+			"ALTER TABLE sms DROP CONSTRAINT IF EXISTS sms_location_text_key;\n"+
+			"ALTER TABLE sms DROP CONSTRAINT IF EXISTS sms_text_check;\n"+
+			"DROP TABLE sms;\n")
+	})
+
+	Convey("the sqlc schema has the constraints", t, func() {
+		schema := GenerateSQL(resource)[2].Output
+		So(schema, ShouldContainSubstring, "    text text NOT NULL,\n"+
+			"    PRIMARY KEY (id),\n"+
+			"    CONSTRAINT sms_location_text_key UNIQUE (locationid, text),\n"+
+			"    CONSTRAINT sms_text_check CHECK (char_length(text) > 0)\n);")
+	})
+
+	Convey("the create test covers unique violations", t, func() {
+		tests := GenerateTests(resource)[0].Output
+		So(tests, ShouldContainSubstring, "\t\"github.com/lib/pq\"\n")
+		So(tests, ShouldContainSubstring, "\t\t\tname: \"fails when sms_location_text_key is violated\",\n")
+		So(tests, ShouldContainSubstring, "\t\t\terr:     &pq.Error{Code: \"23505\", Constraint: \"sms_location_text_key\"},\n")
+	})
+
+	Convey("Validate reports unnamed constraints, taken names and unknown columns", t, func() {
+		invalid := resource
+		invalid.Indexes = Indexes{{Name: "sms_text_check", Type: "BTREE", Columns: []string{"text"}}}
+		invalid.UniqueConstraints = []UniqueConstraint{{Columns: []string{"location_id"}}}
+		invalid.CheckConstraints = []CheckConstraint{{Name: "sms_text_check"}, {Name: "sms_sent_check", Expression: "sent_at IS NULL OR char_length(text) > 0"}}
+
+		So(invalid.Validate(), ShouldResemble, Diagnostics{
+			{Field: "unique_constraints[0].name", Message: "is required"},
+			{Field: "unique_constraints[0].columns[0]", Message: `"location_id" is not an attribute`},
+			{Field: "check_constraints[0].name", Message: `duplicate constraint or index "sms_text_check"`},
+			{Field: "check_constraints[0].expression", Message: "is required"},
+			{Field: "check_constraints[1].expression", Message: `"sent_at" is not an attribute`},
+		})
+	})
+}
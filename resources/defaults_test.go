@@ -0,0 +1,103 @@
+package resources
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource_defaults(t *testing.T) {
+	resource := Resource{
+		Package: "main",
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID", Default: "gen_random_uuid()"},
+				{Name: "text", Type: "text"},
+				{Name: "status", Type: "text", Default: "'queued'", Create: true},
+				{Name: "length", Type: "integer", Generated: "char_length(text)"},
+				{Name: "seq", Type: "bigint", Identity: IdentityAlways},
+				{Name: "position", Type: "integer", Identity: IdentityByDefault, Create: true},
+				{Name: "created_at", Type: "timestamptz", Default: "now()"},
+			},
+		},
+		CrudOptions: []CrudOption{"create"},
+	}
+
+	Convey("the migration renders defaults, generated and identity columns", t, func() {
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldContainSubstring, "\tid UUID NOT NULL DEFAULT gen_random_uuid(),\n"+
+			"\ttext text NOT NULL,\n"+
+			"\tstatus text NOT NULL DEFAULT 'queued',\n"+
+			"\tlength integer GENERATED ALWAYS AS (char_length(text)) STORED NOT NULL,\n"+
+			"\tseq bigint NOT NULL GENERATED ALWAYS AS IDENTITY,\n"+
+			"\tposition integer NOT NULL GENERATED BY DEFAULT AS IDENTITY,\n"+
+			"\tcreated_at timestamptz NOT NULL DEFAULT now(),\n"+
+			"\tPRIMARY KEY (id)\n")
+	})
+
+	Convey("the create query leaves out defaulted, generated and identity columns unless asked for", t, func() {
+		queries := GenerateSQL(resource)[0].Output
+		So(queries, ShouldContainSubstring, "INSERT INTO sms (\n    text,\n    status,\n    position\n) VALUES (\n    $1,\n    $2,\n    $3\n)")
+	})
+
+	Convey("the create message has the same attributes", t, func() {
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "message CreateSms {\n  string Text = 1;\n  string Status = 2;\n  int32 Position = 3;\n}")
+	})
+}
+
+func TestResource_identityDialects(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	defer func() { Database = Postgres{} }()
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "seq", Type: "bigint", Identity: IdentityAlways},
+				{Name: "text", Type: "text"},
+			},
+			PrimaryKey: []string{"seq"},
+		},
+	}
+
+	for _, dialect := range []Dialect{Postgres{}, MySQL{}, SQLite{}} {
+		Database = dialect
+
+		Convey("the "+dialect.Name()+" migration numbers the identity column", t, func() {
+			So(resource.Validate(), ShouldBeEmpty)
+			So(GenerateMigration(resource)[0].Output, ShouldEqual, goldenFile("identity"+dialect.Name()))
+		})
+	}
+
+	Convey("an identity column outside postgres must be the primary key", t, func() {
+		Database = MySQL{}
+		keyless := resource
+		keyless.PrimaryKey = []string{"text"}
+		So(keyless.Validate(), ShouldResemble, Diagnostics{
+			{Field: "attributes[0].identity", Message: "a mysql identity column must be the primary key"},
+		})
+	})
+
+	sqlite3, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	Convey("sqlite numbers the identity key", t, func() {
+		Database = SQLite{}
+		schema := GenerateSQL(resource)[2].Output
+
+		cmd := exec.Command(sqlite3, "-bail", ":memory:")
+		cmd.Stdin = strings.NewReader(schema + "\n" +
+			"INSERT INTO sms (text) VALUES ('hello'), ('world');\n" +
+			"SELECT seq, text FROM sms;\n")
+		out, err := cmd.CombinedOutput()
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "1|hello\n2|world\n")
+	})
+}
@@ -0,0 +1,438 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is the contents of a resource definition file (YAML or JSON)
+//
+//	resources:
+//	  - table_name: sms
+//	    attributes:
+//	      - name: id
+//	        type: UUID
+//	    crud_options: [show, index]
+//
+// A file may also hold a single resource at the top level, or several YAML
+// documents separated by "---". Types registers extra attribute types:
+//
+//	types:
+//	  money:
+//	    sql: numeric(12,2)
+//	    proto: string
+//	    go: string
+//
+// and Defaults sets the length and precision of the types declared without any:
+//
+//	defaults:
+//	  length: 255
+//
+// MigrationFormat names the format migrations are written in and Dialect the
+// database SQL is generated for, goose and postgres when not set:
+//
+//	migration_format: golang-migrate
+//	dialect: mysql
+type Definition struct {
+	Resources       []Resource                 `yaml:"resources,omitempty"`
+	Types           map[AttributeType]TypeInfo `yaml:"types,omitempty"`
+	Defaults        Defaults                   `yaml:"defaults,omitempty"`
+	MigrationFormat string                     `yaml:"migration_format,omitempty"`
+	Dialect         string                     `yaml:"dialect,omitempty"`
+}
+
+// RegisterTypes applies the defaults of the definition and adds its types to
+// the type registry
+func (d Definition) RegisterTypes() error {
+	if err := SetDefaults(d.Defaults); err != nil {
+		return err
+	}
+
+	return RegisterTypes(d.Types)
+}
+
+// Find returns the resource defined for table name
+func (d Definition) Find(name string) (Resource, bool) {
+	for _, resource := range d.Resources {
+		if resource.TableName == name {
+			return resource, true
+		}
+	}
+
+	return Resource{}, false
+}
+
+// Names returns the table names of every defined resource
+func (d Definition) Names() []string {
+	names := make([]string, len(d.Resources))
+	for i, resource := range d.Resources {
+		names[i] = resource.TableName
+	}
+
+	return names
+}
+
+// Encode renders the definition as YAML
+func (d Definition) Encode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(d); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SaveDefinition writes the definition to path, as JSON when path is a .json
+// file and as YAML otherwise
+func SaveDefinition(path string, d Definition) error {
+	data, err := d.Encode()
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		if data, err = yamlToJSON(data); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// yamlToJSON renders a YAML document as indented JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encoded, '\n'), nil
+}
+
+// DecodeError reports the file, line and field a definition failed to decode at
+type DecodeError struct {
+	File  string
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e DecodeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d: %s: %v", e.File, e.Line, e.Field, e.Err)
+}
+
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// LoadDefinition reads the definition file at path
+func LoadDefinition(path string) (Definition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	return ParseDefinition(path, data)
+}
+
+// ParseDefinition decodes data, file is only used to report errors
+func ParseDefinition(file string, data []byte) (Definition, error) {
+	definition := Definition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return definition, fmt.Errorf("%s: %v", file, err)
+		}
+
+		d := definitionDecoder{file: file}
+		doc, err := d.document(&document)
+		if err != nil {
+			return definition, err
+		}
+
+		definition.Resources = append(definition.Resources, doc.Resources...)
+		if doc.Defaults != (Defaults{}) {
+			definition.Defaults = doc.Defaults
+		}
+		if doc.MigrationFormat != "" {
+			definition.MigrationFormat = doc.MigrationFormat
+		}
+		if doc.Dialect != "" {
+			definition.Dialect = doc.Dialect
+		}
+		for name, info := range doc.Types {
+			if definition.Types == nil {
+				definition.Types = map[AttributeType]TypeInfo{}
+			}
+			definition.Types[name] = info
+		}
+	}
+
+	if len(definition.Resources) == 0 && len(definition.Types) == 0 && definition.Defaults == (Defaults{}) {
+		return definition, fmt.Errorf("%s: no resources defined", file)
+	}
+
+	if _, err := LookupMigrationFormat(definition.MigrationFormat); err != nil {
+		return definition, fmt.Errorf("%s: migration_format: %v", file, err)
+	}
+
+	if _, err := LookupDialect(definition.Dialect); err != nil {
+		return definition, fmt.Errorf("%s: dialect: %v", file, err)
+	}
+
+	return definition, nil
+}
+
+type definitionDecoder struct {
+	file string
+}
+
+func (d definitionDecoder) errorf(node *yaml.Node, field string, format string, args ...interface{}) error {
+	return DecodeError{
+		File:  d.file,
+		Line:  node.Line,
+		Field: field,
+		Err:   fmt.Errorf(format, args...),
+	}
+}
+
+// document decodes either a Definition or a single top level Resource
+func (d definitionDecoder) document(document *yaml.Node) (Definition, error) {
+	definition := Definition{}
+
+	node := resolve(document)
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return definition, nil
+		}
+		node = resolve(node.Content[0])
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return definition, d.errorf(node, "", "expected a mapping of resources")
+	}
+
+	if mappingHasKey(node, "resources") || mappingHasKey(node, "types") || mappingHasKey(node, "defaults") ||
+		mappingHasKey(node, "migration_format") || mappingHasKey(node, "dialect") {
+		err := d.decode(node, reflect.ValueOf(&definition).Elem(), "")
+
+		return definition, err
+	}
+
+	resource := Resource{}
+	if err := d.decode(node, reflect.ValueOf(&resource).Elem(), ""); err != nil {
+		return definition, err
+	}
+	definition.Resources = []Resource{resource}
+
+	return definition, nil
+}
+
+// decode walks node into v so errors can report the line and field they occur at
+func (d definitionDecoder) decode(node *yaml.Node, v reflect.Value, field string) error {
+	node = resolve(node)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return d.errorf(node, field, "expected a mapping, got %q", node.Value)
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := joinField(field, key.Value)
+
+			fv, ok := fieldByTag(v, key.Value)
+			if !ok {
+				return d.errorf(key, path, "unknown field")
+			}
+
+			if err := d.decode(value, fv, path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case reflect.Slice:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return nil
+		}
+		if node.Kind != yaml.SequenceNode {
+			return d.errorf(node, field, "expected a list, got %q", node.Value)
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(node.Content), len(node.Content))
+		for i, item := range node.Content {
+			if err := d.decode(item, slice.Index(i), fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+
+		return nil
+	case reflect.Map:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return nil
+		}
+		if node.Kind != yaml.MappingNode {
+			return d.errorf(node, field, "expected a mapping, got %q", node.Value)
+		}
+
+		m := reflect.MakeMapWithSize(v.Type(), len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+			path := joinField(field, node.Content[i].Value)
+
+			if err := d.decode(node.Content[i], key, path); err != nil {
+				return err
+			}
+			if err := d.decode(node.Content[i+1], value, path); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+
+		return nil
+	case reflect.Ptr:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return nil
+		}
+
+		ptr := reflect.New(v.Type().Elem())
+		if err := d.decode(node, ptr.Elem(), field); err != nil {
+			return err
+		}
+		v.Set(ptr)
+
+		return nil
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return d.errorf(node, field, "expected a %s value", kindName(v.Type()))
+	}
+
+	if err := node.Decode(v.Addr().Interface()); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return d.errorf(node, field, "cannot use %q as a %s value", node.Value, kindName(v.Type()))
+		}
+
+		return d.errorf(node, field, "%v", err)
+	}
+
+	return nil
+}
+
+// fieldByTag finds the struct field of v named by its yaml tag, looking into inlined structs
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		tag, inline := yamlTag(sf)
+		if inline {
+			if fv, ok := fieldByTag(v.Field(i), name); ok {
+				return fv, true
+			}
+			continue
+		}
+
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// yamlTag returns the yaml name of the field and whether it is inlined
+func yamlTag(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("yaml")
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+
+	if parts[0] == "" {
+		return strings.ToLower(sf.Name), false
+	}
+
+	return parts[0], false
+}
+
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+
+	return "string"
+}
+
+func joinField(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+
+	return parent + "." + field
+}
+
+func mappingHasKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
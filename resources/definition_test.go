@@ -0,0 +1,285 @@
+package resources
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseDefinition(t *testing.T) {
+	sms := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{
+					Name: "id",
+					Type: "UUID",
+				},
+				{
+					Name:     "text",
+					Type:     "string",
+					Nullable: true,
+				},
+			},
+			Indexes: Indexes{
+				{
+					Name:    "idx_sms_id",
+					Type:    "BTREE",
+					Columns: []string{"id"},
+				},
+			},
+			Owner: "schedule",
+		},
+		CrudOptions: []CrudOption{"show", "index"},
+		Package:     "sms",
+	}
+	setting := Resource{
+		CreateTable: CreateTable{
+			TableName: "setting",
+			Attributes: Attributes{
+				{
+					Name: "id",
+					Type: "UUID",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want Definition
+	}{
+		{
+			name: "decodes a list of resources",
+			data: `
+resources:
+  - table_name: sms
+    attributes:
+      - name: id
+        type: UUID
+      - name: text
+        type: string
+        nullable: true
+    indexes:
+      - name: idx_sms_id
+        type: BTREE
+        columns: [id]
+    owner: schedule
+    crud_options: [show, index]
+    package: sms
+  - table_name: setting
+    attributes:
+      - {name: id, type: UUID}
+`,
+			want: Definition{Resources: []Resource{sms, setting}},
+		},
+		{
+			name: "decodes a single top level resource",
+			data: `
+table_name: setting
+attributes:
+  - name: id
+    type: UUID
+`,
+			want: Definition{Resources: []Resource{setting}},
+		},
+		{
+			name: "decodes several documents",
+			data: `
+table_name: setting
+attributes: [{name: id, type: UUID}]
+---
+resources:
+  - table_name: setting
+    attributes: [{name: id, type: UUID}]
+`,
+			want: Definition{Resources: []Resource{setting, setting}},
+		},
+		{
+			name: "decodes json",
+			data: `{
+	"resources": [
+		{
+			"table_name": "setting",
+			"attributes": [{"name": "id", "type": "UUID"}]
+		}
+	]
+}`,
+			want: Definition{Resources: []Resource{setting}},
+		},
+		{
+			name: "decodes types",
+			data: `
+types:
+  money:
+    sql: numeric(12,2)
+    proto: string
+    go: string
+    test_value: '"1.00"'
+---
+table_name: setting
+attributes: [{name: id, type: UUID}]
+`,
+			want: Definition{
+				Resources: []Resource{setting},
+				Types: map[AttributeType]TypeInfo{
+					"money": {SQL: "numeric(12,2)", Proto: "string", Go: "string", TestValue: `"1.00"`},
+				},
+			},
+		},
+		{
+			name: "decodes defaults",
+			data: `
+defaults:
+  length: 255
+  precision: 12
+  scale: 2
+`,
+			want: Definition{Defaults: Defaults{Length: 255, Precision: 12, Scale: 2}},
+		},
+		{
+			name: "decodes the migration format",
+			data: `
+migration_format: dbmate
+resources:
+  - table_name: setting
+`,
+			want: Definition{
+				Resources:       []Resource{{CreateTable: CreateTable{TableName: "setting"}}},
+				MigrationFormat: "dbmate",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseDefinition", t, func() {
+				got, err := ParseDefinition("sms.yaml", []byte(tt.data))
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		})
+	}
+}
+
+func TestParseDefinition_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want DecodeError
+	}{
+		{
+			name: "reports unknown fields",
+			data: `
+resources:
+  - table_name: sms
+    attributes:
+      - name: id
+        kind: UUID
+`,
+			want: DecodeError{
+				File:  "sms.yaml",
+				Line:  6,
+				Field: "resources[0].attributes[0].kind",
+			},
+		},
+		{
+			name: "reports values of the wrong type",
+			data: `
+table_name: sms
+attributes:
+  - name: id
+    type: UUID
+    nullable: sometimes
+`,
+			want: DecodeError{
+				File:  "sms.yaml",
+				Line:  6,
+				Field: "attributes[0].nullable",
+			},
+		},
+		{
+			name: "reports a mapping where a list is expected",
+			data: `
+table_name: sms
+indexes:
+  name: idx
+`,
+			want: DecodeError{
+				File:  "sms.yaml",
+				Line:  4,
+				Field: "indexes",
+			},
+		},
+		{
+			name: "reports unknown type fields",
+			data: `
+types:
+  money:
+    sql: numeric
+    rust: String
+`,
+			want: DecodeError{
+				File:  "sms.yaml",
+				Line:  5,
+				Field: "types.money.rust",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseDefinition", t, func() {
+				_, err := ParseDefinition("sms.yaml", []byte(tt.data))
+
+				var got DecodeError
+				So(errors.As(err, &got), ShouldBeTrue)
+				So(got.File, ShouldEqual, tt.want.File)
+				So(got.Line, ShouldEqual, tt.want.Line)
+				So(got.Field, ShouldEqual, tt.want.Field)
+			})
+		})
+	}
+
+	Convey("ParseDefinition fails without resources", t, func() {
+		_, err := ParseDefinition("empty.yaml", []byte(""))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("ParseDefinition fails on unknown migration formats", t, func() {
+		_, err := ParseDefinition("sms.yaml", []byte("migration_format: sqitch\nresources:\n  - table_name: sms\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, `sms.yaml: migration_format: unknown migration format "sqitch"`)
+	})
+}
+
+func TestSaveDefinition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	definition := Definition{Resources: []Resource{{
+		CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{{Name: "id", Type: "UUID"}}},
+		CrudOptions: []CrudOption{"show"},
+	}}}
+
+	Convey("SaveDefinition keeps the format of the file", t, func() {
+		for _, name := range []string{"sms.yaml", "sms.json"} {
+			path := filepath.Join(dir, name)
+			So(SaveDefinition(path, definition), ShouldBeNil)
+
+			loaded, err := LoadDefinition(path)
+			So(err, ShouldBeNil)
+			So(loaded, ShouldResemble, definition)
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "sms.json"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldStartWith, "{\n  \"resources\": [\n")
+	})
+}
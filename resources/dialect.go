@@ -0,0 +1,326 @@
+package resources
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dialect is the SQL flavor migrations, sqlc schemas, queries and their tests
+// are generated in
+type Dialect interface {
+	Name() string                                    // names the dialect in definitions and -dialect (i.e. postgres)
+	Engine() string                                  // sqlc's engine (i.e. postgresql)
+	ColumnType(info TypeInfo) string                 // column type of a registered type
+	DefaultValue(expression string) string           // DEFAULT of a column defaulting to expression
+	Identity(identity string) string                 // clause of an identity column (i.e. AUTO_INCREMENT)
+	GoType(info TypeInfo) TypeInfo                   // info with the go types sqlc generates for the column type
+	EnumType(enum Enum) string                       // column type of the attributes of enum
+	EnumTypes() bool                                 // whether enums are types created before their tables
+	GoEnums() bool                                   // whether sqlc generates go types for enums
+	TableOptions() string                            // follows the closing parenthesis of CREATE TABLE
+	CreateIndex(table string, index Index) string    // statement creating index on table
+	DropConstraint(table string, name string) string // empty when dropping the table is enough
+	Owners() bool                                    // whether tables are given to their Owner
+	Placeholder(position int) string                 // query parameter at position, from 1 (i.e. $1)
+	Returning() bool                                 // whether INSERT returns the created row
+	UniqueViolation(constraint string) string        // go expression of the driver error of a violated constraint
+	ErrorImport() string                             // package of UniqueViolation
+}
+
+// Postgres generates for PostgreSQL, goils' original output
+type Postgres struct{}
+
+func (Postgres) Name() string   { return "postgres" }
+func (Postgres) Engine() string { return "postgresql" }
+
+func (Postgres) ColumnType(info TypeInfo) string       { return info.SQL }
+func (Postgres) DefaultValue(expression string) string { return expression }
+func (Postgres) GoType(info TypeInfo) TypeInfo         { return info }
+func (Postgres) EnumType(enum Enum) string             { return enum.Name }
+func (Postgres) EnumTypes() bool                       { return true }
+func (Postgres) GoEnums() bool                         { return true }
+
+func (Postgres) Identity(identity string) string {
+	return fmt.Sprintf(" GENERATED %s AS IDENTITY", identity)
+}
+
+func (Postgres) TableOptions() string { return "\nWITH\n(\n\tOIDS=FALSE\n)" }
+
+func (Postgres) CreateIndex(table string, index Index) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s\n\tON %s\n\tUSING %s\n(%s);", index.Name, table, index.Type, index.SqlIndex())
+}
+
+func (Postgres) DropConstraint(table string, name string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table, name)
+}
+
+func (Postgres) Owners() bool                    { return true }
+func (Postgres) Placeholder(position int) string { return fmt.Sprintf("$%d", position) }
+func (Postgres) Returning() bool                 { return true }
+
+func (Postgres) UniqueViolation(constraint string) string {
+	return fmt.Sprintf(`&pq.Error{Code: "23505", Constraint: "%s"}`, constraint)
+}
+
+func (Postgres) ErrorImport() string { return "github.com/lib/pq" }
+
+// MySQL generates for MySQL 8 and InnoDB tables. Enums are inline ENUM
+// columns, UUIDs CHAR(36) unless the UUID type is registered with another mysql
+// type (i.e. BINARY(16)), defaults other than literals are parenthesised and
+// INSERT does not return the created row
+type MySQL struct{}
+
+func (MySQL) Name() string   { return "mysql" }
+func (MySQL) Engine() string { return "mysql" }
+
+func (MySQL) ColumnType(info TypeInfo) string {
+	if info.MySQL != "" {
+		return info.MySQL
+	}
+
+	return info.SQL
+}
+
+// mysqlLiteral matches the defaults mysql takes without parentheses, numbers,
+// strings, hexadecimal and bit values, NULL, booleans and CURRENT_TIMESTAMP and
+// its synonyms
+var mysqlLiteral = regexp.MustCompile(`(?i)^([+-]?(\d+(\.\d*)?|\.\d+)(e[+-]?\d+)?|0x[0-9a-f]+|'([^']|'')*'|x'[0-9a-f]*'|b'[01]*'|null|true|false|(current_timestamp|localtime|localtimestamp)(\(\d*\))?|now\(\d*\))$`)
+
+// DefaultValue parenthesises expressions (i.e. (UUID())), mysql 8 only taking
+// literals as they are
+func (MySQL) DefaultValue(expression string) string {
+	return parenthesise(expression, mysqlLiteral)
+}
+
+// Identity is AUTO_INCREMENT, taking given values as BY DEFAULT does
+func (MySQL) Identity(string) string { return " AUTO_INCREMENT" }
+
+// GoType returns the go types sqlc generates for the mysql column type (i.e.
+// string for CHAR(36) UUIDs, float64 for FLOAT), registered types of other
+// column types keep theirs
+func (m MySQL) GoType(info TypeInfo) TypeInfo {
+	column := strings.ToUpper(m.ColumnType(info))
+	typ := column
+	if i := strings.IndexAny(typ, "( "); i >= 0 {
+		typ = typ[:i]
+	}
+
+	goImport, goNullImport := "", sqlImport
+	switch {
+	case typ == "CHAR" || typ == "VARCHAR" || strings.HasSuffix(typ, "TEXT") || typ == "DECIMAL" || typ == "NUMERIC":
+		if info.Go != "string" {
+			info.TestValue = `"text"`
+		}
+		info.Go, info.GoNull = "string", "sql.NullString"
+	case typ == "BOOLEAN" || typ == "BOOL" || column == "TINYINT(1)":
+		info.Go, info.GoNull, info.TestValue = "bool", "sql.NullBool", "true"
+	case typ == "TINYINT":
+		info.Go, info.GoNull, info.TestValue = "int8", "sql.NullInt16", "int8(1)"
+	case typ == "SMALLINT":
+		info.Go, info.GoNull, info.TestValue = "int16", "sql.NullInt16", "int16(1)"
+	case typ == "INT" || typ == "INTEGER" || typ == "MEDIUMINT":
+		info.Go, info.GoNull, info.TestValue = "int32", "sql.NullInt32", "int32(1)"
+	case typ == "BIGINT":
+		info.Go, info.GoNull, info.TestValue = "int64", "sql.NullInt64", "int64(1)"
+	case typ == "FLOAT" || typ == "DOUBLE" || typ == "REAL":
+		info.Go, info.GoNull, info.TestValue = "float64", "sql.NullFloat64", "float64(1.5)"
+	case strings.HasSuffix(typ, "BLOB") || strings.HasSuffix(typ, "BINARY"):
+		info.Go, info.GoNull, info.TestValue = "[]byte", "", `[]byte("bytes")`
+		goNullImport = ""
+	case typ == "DATE" || typ == "DATETIME" || typ == "TIMESTAMP" || typ == "TIME":
+		if info.Go != "time.Time" {
+			info.TestValue = "time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)"
+		}
+		info.Go, info.GoNull = "time.Time", "sql.NullTime"
+		goImport = "time"
+	case typ == "JSON":
+		info.Go, info.GoNull, info.TestValue = "json.RawMessage", "", "json.RawMessage(`{}`)"
+		goImport, goNullImport = "encoding/json", ""
+	default:
+		return info
+	}
+
+	info.GoImport, info.GoNullImport = goImport, goNullImport
+
+	return info
+}
+
+func (MySQL) EnumType(enum Enum) string { return "ENUM(" + enum.SQLValues() + ")" }
+func (MySQL) EnumTypes() bool           { return false }
+func (MySQL) GoEnums() bool             { return true }
+
+func (MySQL) TableOptions() string { return " ENGINE=InnoDB" }
+
+// CreateIndex keeps the index type when InnoDB has it, other types (i.e. GIN)
+// are left to the default BTREE
+func (MySQL) CreateIndex(table string, index Index) string {
+	using := ""
+	if typ := strings.ToUpper(index.Type); typ == "BTREE" || typ == "HASH" {
+		using = " USING " + typ
+	}
+
+	return fmt.Sprintf("CREATE INDEX %s\n\tON %s (%s)%s;", index.Name, table, index.SqlIndex(), using)
+}
+
+func (MySQL) DropConstraint(string, string) string { return "" }
+func (MySQL) Owners() bool                         { return false }
+func (MySQL) Placeholder(int) string               { return "?" }
+func (MySQL) Returning() bool                      { return false }
+
+func (MySQL) UniqueViolation(constraint string) string {
+	return fmt.Sprintf(`&mysql.MySQLError{Number: 1062, Message: "Duplicate entry for key '%s'"}`, constraint)
+}
+
+func (MySQL) ErrorImport() string { return "github.com/go-sql-driver/mysql" }
+
+// SQLite generates for SQLite (i.e. in-process databases of lightweight
+// services and tests). Columns are declared with the type affinities (UUIDs and
+// enums are TEXT, the go values of enums strings), defaults other than literals
+// are parenthesised and the sqlc tests are joined by tests running the show
+// and index queries against modernc.org/sqlite
+type SQLite struct{}
+
+func (SQLite) Name() string   { return "sqlite" }
+func (SQLite) Engine() string { return "sqlite" }
+
+func (SQLite) ColumnType(info TypeInfo) string {
+	if info.SQLite != "" {
+		return info.SQLite
+	}
+
+	return info.SQL
+}
+
+// sqliteLiteral matches the defaults sqlite takes without parentheses, numbers,
+// strings, blobs, NULL, booleans and the current time
+var sqliteLiteral = regexp.MustCompile(`(?i)^([+-]?(\d+(\.\d*)?|\.\d+)(e[+-]?\d+)?|0x[0-9a-f]+|'([^']|'')*'|x'[0-9a-f]*'|null|true|false|current_(time|date|timestamp))$`)
+
+// DefaultValue parenthesises expressions (i.e. (datetime('now'))), sqlite
+// only taking literals as they are
+func (SQLite) DefaultValue(expression string) string {
+	return parenthesise(expression, sqliteLiteral)
+}
+
+// parenthesise returns expression within parentheses unless it is a literal or
+// already parenthesised
+func parenthesise(expression string, literal *regexp.Regexp) string {
+	expression = strings.TrimSpace(expression)
+	if literal.MatchString(expression) || parenthesised(expression) {
+		return expression
+	}
+
+	return "(" + expression + ")"
+}
+
+// parenthesised reports whether expression is wholly within parentheses
+func parenthesised(expression string) bool {
+	if !strings.HasPrefix(expression, "(") {
+		return false
+	}
+
+	depth, quoted := 0, false
+	for i, r := range expression {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i == len(expression)-1
+			}
+		}
+	}
+
+	return false
+}
+
+// Identity is left to the key, an INTEGER primary key being an alias of the
+// rowid sqlite numbers
+func (SQLite) Identity(string) string { return "" }
+
+// GoType returns the go types sqlc generates for the affinity of the column
+// (i.e. string for UUIDs, arrays and other TEXT columns, int64 for integers)
+func (s SQLite) GoType(info TypeInfo) TypeInfo {
+	switch s.ColumnType(info) {
+	case "TEXT":
+		if info.Go != "string" {
+			info.TestValue = `"text"`
+		}
+		info.Go, info.GoNull = "string", "sql.NullString"
+	case "INTEGER":
+		info.Go, info.GoNull, info.TestValue = "int64", "sql.NullInt64", "int64(1)"
+	case "REAL":
+		info.Go, info.GoNull, info.TestValue = "float64", "sql.NullFloat64", "float64(1.5)"
+	case "BLOB":
+		info.Go, info.GoNull, info.TestValue = "[]byte", "", `[]byte("bytes")`
+	default:
+		return info
+	}
+
+	info.GoImport, info.GoNullImport = "", ""
+	if info.GoNull != "" {
+		info.GoNullImport = sqlImport
+	}
+
+	return info
+}
+
+func (SQLite) EnumType(Enum) string { return "TEXT" }
+func (SQLite) EnumTypes() bool      { return false }
+func (SQLite) GoEnums() bool        { return false }
+func (SQLite) TableOptions() string { return "" }
+
+func (SQLite) CreateIndex(table string, index Index) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s\n\tON %s (%s);", index.Name, table, index.SqlIndex())
+}
+
+func (SQLite) DropConstraint(string, string) string { return "" }
+func (SQLite) Owners() bool                         { return false }
+func (SQLite) Placeholder(int) string               { return "?" }
+func (SQLite) Returning() bool                      { return true }
+
+func (SQLite) UniqueViolation(constraint string) string {
+	return fmt.Sprintf(`errors.New("UNIQUE constraint failed: %s")`, constraint)
+}
+
+func (SQLite) ErrorImport() string { return "errors" }
+
+// Dialects are the dialects SQL can be generated in, by name
+var Dialects = map[string]Dialect{
+	"postgres": Postgres{},
+	"mysql":    MySQL{},
+	"sqlite":   SQLite{},
+}
+
+// Database is the dialect SQL is generated in (i.e. set by -dialect)
+var Database Dialect = Postgres{}
+
+// LookupDialect returns the dialect named name, postgres when name is empty
+func LookupDialect(name string) (Dialect, error) {
+	if name == "" {
+		return Postgres{}, nil
+	}
+
+	dialect, ok := Dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q (expected one of %s)", name, strings.Join(DialectNames(), ", "))
+	}
+
+	return dialect, nil
+}
+
+// DialectNames returns the names of the dialects, sorted
+func DialectNames() []string {
+	names := make([]string, 0, len(Dialects))
+	for name := range Dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
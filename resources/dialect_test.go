@@ -0,0 +1,266 @@
+package resources
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMySQL(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	Database = MySQL{}
+	defer func() { Database = Postgres{} }()
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID", References: &Reference{Table: "location", OnDelete: "CASCADE"}},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}},
+				{Name: "created_at", Type: "timestamptz", Default: "CURRENT_TIMESTAMP"},
+			},
+			Indexes:           Indexes{{Name: "idx_sms_text", Type: "GIN", Columns: []string{"text"}}},
+			PrimaryKey:        []string{"id"},
+			UniqueConstraints: []UniqueConstraint{{Name: "sms_location_text_key", Columns: []string{"locationid", "text"}}},
+			Owner:             "schedule",
+		},
+		Package:     "main",
+		CrudOptions: []CrudOption{"show", "create"},
+	}
+
+	Convey("GenerateMigration creates an InnoDB table without types or owner", t, func() {
+		generated := GenerateMigration(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated[0].Output, ShouldEqual, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"CREATE TABLE IF NOT EXISTS sms\n(\n"+
+			"\tid CHAR(36) NOT NULL,\n"+
+			"\tlocationid CHAR(36) NOT NULL,\n"+
+			"\ttext TEXT,\n"+
+			"\tstatus ENUM('queued', 'sent') NOT NULL,\n"+
+			"\tcreated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,\n"+
+			"\tPRIMARY KEY (id),\n"+
+			"\tCONSTRAINT sms_locationid_fkey FOREIGN KEY (locationid) REFERENCES location (id) ON DELETE CASCADE,\n"+
+			"\tCONSTRAINT sms_location_text_key UNIQUE (locationid, text)\n"+
+			") ENGINE=InnoDB;\n"+
+			"CREATE INDEX idx_sms_text\n\tON sms (text);\n"+
+			"CREATE INDEX idx_sms_locationid\n\tON sms (locationid) USING BTREE;\n"+
+			"-- +goose StatementEnd\n\n"+
+			"-- +goose Down\n-- +goose StatementBegin\n"+
+			"DROP TABLE sms;\n"+
+			"-- +goose StatementEnd")
+	})
+
+	Convey("DefaultValue parenthesises the defaults that are not literals", t, func() {
+		So(MySQL{}.DefaultValue("CURRENT_TIMESTAMP"), ShouldEqual, "CURRENT_TIMESTAMP")
+		So(MySQL{}.DefaultValue("now(6)"), ShouldEqual, "now(6)")
+		So(MySQL{}.DefaultValue("'queued'"), ShouldEqual, "'queued'")
+		So(MySQL{}.DefaultValue("-1.5"), ShouldEqual, "-1.5")
+		So(MySQL{}.DefaultValue("UUID()"), ShouldEqual, "(UUID())")
+		So(MySQL{}.DefaultValue("CURRENT_DATE"), ShouldEqual, "(CURRENT_DATE)")
+		So(MySQL{}.DefaultValue("(RAND() * 10)"), ShouldEqual, "(RAND() * 10)")
+	})
+
+	Convey("GenerateSQL uses ? parameters and sqlc's mysql engine", t, func() {
+		generated := GenerateSQL(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated[0].Output, ShouldContainSubstring, "WHERE id = ? LIMIT 1;")
+		So(generated[0].Output, ShouldContainSubstring, "-- name: CreateSms :exec\nINSERT INTO sms (\n"+
+			"    id,\n    locationid,\n    text,\n    status\n) VALUES (\n    ?,\n    ?,\n    ?,\n    ?\n);")
+		So(generated[1].Output, ShouldContainSubstring, `"engine": "mysql"`)
+		So(generated[2].Output, ShouldNotContainSubstring, "CREATE TYPE")
+	})
+
+	Convey("GenerateTests expects the create query to be executed", t, func() {
+		generated := GenerateTests(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated[0].Output, ShouldContainSubstring, `sqlx.NewDb(mockDB, "mysql")`)
+		So(generated[0].Output, ShouldContainSubstring, `query := mock.ExpectExec("^INSERT INTO (.+)")`)
+		So(generated[0].Output, ShouldContainSubstring, `err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry for key 'sms_location_text_key'"},`)
+		So(generated[0].Output, ShouldContainSubstring, `"github.com/go-sql-driver/mysql"`)
+	})
+
+	Convey("generated columns take their expression right after the type", t, func() {
+		So(Attribute{Name: "text_length", Type: "integer", Generated: "char_length(text)"}.ToTemplate(), ShouldEqual,
+			"text_length integer GENERATED ALWAYS AS (char_length(text)) STORED NOT NULL")
+	})
+
+	Convey("GoType follows sqlc's types of the mysql column types", t, func() {
+		So(Attribute{Name: "id", Type: "UUID"}.GoType(), ShouldEqual, "string")
+		So(Attribute{Name: "id", Type: "UUID"}.TestValue(), ShouldEqual, `"text"`)
+		So(Attribute{Name: "id", Type: "UUID", Nullable: true}.GoType(), ShouldEqual, "sql.NullString")
+		So(Attribute{Name: "text", Type: "string(255)"}.GoType(), ShouldEqual, "string")
+		So(Attribute{Name: "sent", Type: "boolean", Nullable: true}.GoType(), ShouldEqual, "sql.NullBool")
+		So(Attribute{Name: "score", Type: "real"}.GoType(), ShouldEqual, "float64")
+		So(Attribute{Name: "address", Type: "inet"}.GoType(), ShouldEqual, "string")
+		So(Attribute{Name: "payload", Type: "jsonb", Nullable: true}.GoType(), ShouldEqual, "json.RawMessage")
+		So(Attribute{Name: "created_at", Type: "timestamptz", Nullable: true}.GoType(), ShouldEqual, "sql.NullTime")
+	})
+
+	Convey("GenerateAlterMigration is only generated for postgres", t, func() {
+		generated := GenerateAlterMigration(Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{{Name: "id", Type: "UUID"}}}}, resource)
+		So(generated.AnyErrors(), ShouldBeTrue)
+		So(generated[0].Error.Error(), ShouldEqual, "alter migrations are only generated for postgres, not mysql")
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	Database = SQLite{}
+	defer func() { Database = Postgres{} }()
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID", References: &Reference{Table: "location"}},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}},
+				{Name: "created_at", Type: "timestamptz"},
+				{Name: "text_length", Type: "integer", Generated: "length(text)"},
+			},
+			Indexes:           Indexes{{Name: "idx_sms_text", Type: "GIN", Columns: []string{"text"}}},
+			PrimaryKey:        []string{"id"},
+			UniqueConstraints: []UniqueConstraint{{Name: "sms_location_text_key", Columns: []string{"locationid", "text"}}},
+			Owner:             "schedule",
+		},
+		Package:     "main",
+		CrudOptions: []CrudOption{"show", "index", "create"},
+	}
+
+	Convey("GenerateMigration creates a table of type affinities without types or owner", t, func() {
+		generated := GenerateMigration(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated[0].Output, ShouldEqual, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"CREATE TABLE IF NOT EXISTS sms\n(\n"+
+			"\tid TEXT NOT NULL,\n"+
+			"\tlocationid TEXT NOT NULL,\n"+
+			"\ttext TEXT,\n"+
+			"\tstatus TEXT NOT NULL,\n"+
+			"\tcreated_at DATETIME NOT NULL,\n"+
+			"\ttext_length INTEGER GENERATED ALWAYS AS (length(text)) STORED NOT NULL,\n"+
+			"\tPRIMARY KEY (id),\n"+
+			"\tCONSTRAINT sms_locationid_fkey FOREIGN KEY (locationid) REFERENCES location (id),\n"+
+			"\tCONSTRAINT sms_location_text_key UNIQUE (locationid, text)\n"+
+			");\n"+
+			"CREATE INDEX IF NOT EXISTS idx_sms_text\n\tON sms (text);\n"+
+			"CREATE INDEX IF NOT EXISTS idx_sms_locationid\n\tON sms (locationid);\n"+
+			"-- +goose StatementEnd\n\n"+
+			"-- +goose Down\n-- +goose StatementBegin\n"+
+			"DROP TABLE sms;\n"+
+			"-- +goose StatementEnd")
+	})
+
+	Convey("GenerateSQL uses ? parameters and sqlc's sqlite engine", t, func() {
+		generated := GenerateSQL(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated[0].Output, ShouldContainSubstring, "WHERE id = ? LIMIT 1;")
+		So(generated[0].Output, ShouldContainSubstring, "-- name: CreateSms :one\n")
+		So(generated[0].Output, ShouldContainSubstring, "RETURNING *;")
+		So(generated[1].Output, ShouldContainSubstring, `"engine": "sqlite"`)
+		So(generated[2].Output, ShouldNotContainSubstring, "CREATE TYPE")
+	})
+
+	Convey("GenerateTests adds the tests running the queries in SQLite", t, func() {
+		generated := GenerateTests(resource)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated, ShouldHaveLength, 2)
+		So(generated[0].Output, ShouldContainSubstring, `sqlx.NewDb(mockDB, "sqlite")`)
+		So(generated[0].Output, ShouldContainSubstring, `err:     errors.New("UNIQUE constraint failed: sms_location_text_key"),`)
+
+		So(generated[1].FileOut, ShouldEqual, "queries_sqlite_test.go")
+		So(generated[1].Output, ShouldContainSubstring, `_ "modernc.org/sqlite"`)
+		So(generated[1].Output, ShouldContainSubstring, `db.Exec("INSERT INTO sms (id, locationid, text, status, created_at) VALUES (?, ?, ?, ?, ?)",`+
+			"\n\t\texpectedId, expectedLocationid, expectedText, expectedStatus, expectedCreatedAt)")
+		So(generated[1].Output, ShouldContainSubstring, "got, err := New(db).GetSms(context.Background(), expectedId)")
+		So(generated[1].Output, ShouldContainSubstring, "got, err := New(db).ListSmsByLocation(context.Background(), expectedLocationid)")
+		So(generated[1].Output, ShouldNotContainSubstring, "CreateSms")
+	})
+
+	Convey("GoType follows sqlc's types of the column affinities", t, func() {
+		So(Attribute{Name: "id", Type: "UUID"}.GoType(), ShouldEqual, "string")
+		So(Attribute{Name: "id", Type: "UUID"}.TestValue(), ShouldEqual, `"text"`)
+		So(Attribute{Name: "status", Type: "sms_status", Values: []string{"queued"}}.TestValue(), ShouldEqual, `"queued"`)
+		So(Attribute{Name: "status", Type: "sms_status", Values: []string{"queued"}, Nullable: true}.GoType(), ShouldEqual, "sql.NullString")
+		So(Attribute{Name: "tries", Type: "integer", Nullable: true}.GoType(), ShouldEqual, "sql.NullInt64")
+		So(Attribute{Name: "score", Type: "real"}.GoType(), ShouldEqual, "float64")
+		So(Attribute{Name: "tags", Type: "text[]"}.GoType(), ShouldEqual, "string")
+		So(Attribute{Name: "created_at", Type: "timestamptz"}.GoType(), ShouldEqual, "time.Time")
+	})
+}
+
+func TestSQLite_schema(t *testing.T) {
+	Database = SQLite{}
+	defer func() { Database = Postgres{} }()
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}, Default: "'queued'"},
+				{Name: "tries", Type: "integer", Default: "0"},
+				{Name: "created_at", Type: "timestamptz", Default: "datetime('now')"},
+				{Name: "text_length", Type: "integer", Generated: "length(text)", Nullable: true},
+			},
+			Indexes:          Indexes{{Name: "idx_sms_status", Type: "BTREE", Columns: []string{"status"}}},
+			PrimaryKey:       []string{"id"},
+			CheckConstraints: []CheckConstraint{{Name: "sms_tries_check", Expression: "tries >= 0"}},
+		},
+		CrudOptions: []CrudOption{"show"},
+	}
+
+	Convey("DefaultValue parenthesises the defaults that are not literals", t, func() {
+		So(resource.Attributes[2].ToTemplate(), ShouldEqual, "status TEXT NOT NULL DEFAULT 'queued'")
+		So(resource.Attributes[3].ToTemplate(), ShouldEqual, "tries INTEGER NOT NULL DEFAULT 0")
+		So(resource.Attributes[4].ToTemplate(), ShouldEqual, "created_at DATETIME NOT NULL DEFAULT (datetime('now'))")
+		So(SQLite{}.DefaultValue("CURRENT_TIMESTAMP"), ShouldEqual, "CURRENT_TIMESTAMP")
+		So(SQLite{}.DefaultValue("(1 + 1)"), ShouldEqual, "(1 + 1)")
+		So(SQLite{}.DefaultValue("(1) + (1)"), ShouldEqual, "((1) + (1))")
+	})
+
+	sqlite3, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	Convey("the generated schema loads into sqlite and fills the defaults", t, func() {
+		schema := GenerateSQL(resource)[2].Output
+
+		cmd := exec.Command(sqlite3, "-bail", ":memory:")
+		cmd.Stdin = strings.NewReader(schema + "\n" +
+			"INSERT INTO sms (id, text) VALUES ('8a9b3f6e', 'hello');\n" +
+			"SELECT status, tries, created_at IS NOT NULL, text_length FROM sms;\n")
+		out, err := cmd.CombinedOutput()
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "queued|0|1|5\n")
+	})
+}
+
+func TestLookupDialect(t *testing.T) {
+	Convey("LookupDialect returns postgres by default", t, func() {
+		dialect, err := LookupDialect("")
+		So(err, ShouldBeNil)
+		So(dialect, ShouldResemble, Postgres{})
+
+		dialect, err = LookupDialect("mysql")
+		So(err, ShouldBeNil)
+		So(dialect, ShouldResemble, MySQL{})
+
+		dialect, err = LookupDialect("sqlite")
+		So(err, ShouldBeNil)
+		So(dialect, ShouldResemble, SQLite{})
+
+		_, err = LookupDialect("oracle")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `unknown dialect "oracle" (expected one of mysql, postgres, sqlite)`)
+	})
+}
@@ -0,0 +1,250 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AlterTable is the migration turning one version of a table into another, Down
+// being the exact inverse of Up
+type AlterTable struct {
+	TableName string
+	Up        TableChanges
+	Down      TableChanges
+}
+
+// TableChanges are the changes of a table, in the order they are applied:
+// constraints and indexes are dropped before their columns change and added
+// back once the columns exist
+type TableChanges struct {
+	TableName         string
+	CreateEnums       []Enum
+	RenameColumns     []Rename
+	RenameConstraints []Rename
+	RenameIndexes     []Rename
+	DropConstraints   []string // constraint names
+	DropIndexes       []string // index names
+	AddColumns        []string // column definitions (i.e. status text NOT NULL)
+	AlterColumns      []string // column alterations (i.e. text DROP NOT NULL)
+	DropColumns       []string // column names
+	DropEnums         []Enum
+	AddConstraints    []string // constraint definitions
+	CreateIndexes     Indexes
+}
+
+// Empty returns whether there is nothing to change
+func (t TableChanges) Empty() bool {
+	return len(t.CreateEnums) == 0 && len(t.RenameColumns) == 0 && len(t.RenameConstraints) == 0 &&
+		len(t.RenameIndexes) == 0 && len(t.DropConstraints) == 0 && len(t.DropIndexes) == 0 &&
+		len(t.AddColumns) == 0 && len(t.AlterColumns) == 0 && len(t.DropColumns) == 0 &&
+		len(t.DropEnums) == 0 && len(t.AddConstraints) == 0 && len(t.CreateIndexes) == 0
+}
+
+// Diff returns the migration turning the table of from into the one of to.
+// Columns are matched by name, a renamed column is dropped and added
+func Diff(from, to Resource) (AlterTable, error) {
+	if from.TableName != to.TableName {
+		return AlterTable{}, fmt.Errorf("cannot diff table %q against %q", to.TableName, from.TableName)
+	}
+
+	up, err := changes(from, to)
+	if err != nil {
+		return AlterTable{}, err
+	}
+
+	down, err := changes(to, from)
+	if err != nil {
+		return AlterTable{}, err
+	}
+
+	return AlterTable{TableName: to.TableName, Up: up, Down: down}, nil
+}
+
+// changes returns the changes turning the table of from into the one of to
+func changes(from, to Resource) (TableChanges, error) {
+	t := TableChanges{TableName: to.TableName}
+
+	fromEnums, toEnums := enumsByName(from), enumsByName(to)
+	for _, enum := range to.Enums() {
+		previous, ok := fromEnums[enum.Name]
+		switch {
+		case !ok:
+			t.CreateEnums = append(t.CreateEnums, enum)
+		case strings.Join(previous.Values, ",") != strings.Join(enum.Values, ","):
+			return t, fmt.Errorf("enum %q changes values, ALTER TYPE is not generated", enum.Name)
+		}
+	}
+	for _, enum := range from.Enums() {
+		if _, ok := toEnums[enum.Name]; !ok {
+			t.DropEnums = append(t.DropEnums, enum)
+		}
+	}
+
+	fromConstraints, toConstraints := from.constraints(), to.constraints()
+	for _, constraint := range fromConstraints {
+		if definition, ok := findConstraint(toConstraints, constraint.name); !ok || !sameSQL(definition, constraint.definition) {
+			t.DropConstraints = append(t.DropConstraints, constraint.name)
+		}
+	}
+	for _, constraint := range toConstraints {
+		if definition, ok := findConstraint(fromConstraints, constraint.name); !ok || !sameSQL(definition, constraint.definition) {
+			t.AddConstraints = append(t.AddConstraints, constraint.definition)
+		}
+	}
+
+	fromIndexes, toIndexes := from.AllIndexes(), to.AllIndexes()
+	for _, index := range fromIndexes {
+		if !hasIndex(toIndexes, index) {
+			t.DropIndexes = append(t.DropIndexes, index.Name)
+		}
+	}
+	for _, index := range toIndexes {
+		if !hasIndex(fromIndexes, index) {
+			t.CreateIndexes = append(t.CreateIndexes, index)
+		}
+	}
+
+	// a generated expression cannot be altered, the column is in both
+	// DropColumns and AddColumns and the template drops it first. Generated
+	// columns are added after and dropped before the columns they use
+	for _, attr := range generatedLast(to.Attributes) {
+		i := indexOfAttribute(from.Attributes, attr.Name)
+		if i < 0 || from.Attributes[i].Generated != attr.Generated {
+			t.AddColumns = append(t.AddColumns, attr.ToTemplate())
+			continue
+		}
+
+		t.AlterColumns = append(t.AlterColumns, alterColumn(from.Attributes[i], attr)...)
+	}
+	for _, attr := range generatedFirst(from.Attributes) {
+		i := indexOfAttribute(to.Attributes, attr.Name)
+		if i < 0 || to.Attributes[i].Generated != attr.Generated {
+			t.DropColumns = append(t.DropColumns, attr.Name)
+		}
+	}
+
+	return t, nil
+}
+
+// generatedLast returns the attributes with the generated ones after the
+// others, postgres not letting a generated column use another
+func generatedLast(attributes Attributes) Attributes {
+	return append(generated(attributes, false), generated(attributes, true)...)
+}
+
+// generatedFirst returns the attributes with the generated ones first
+func generatedFirst(attributes Attributes) Attributes {
+	return append(generated(attributes, true), generated(attributes, false)...)
+}
+
+// generated returns the attributes that are generated, or the ones that are not
+func generated(attributes Attributes, is bool) Attributes {
+	filtered := make(Attributes, 0, len(attributes))
+	for _, attr := range attributes {
+		if (attr.Generated != "") == is {
+			filtered = append(filtered, attr)
+		}
+	}
+
+	return filtered
+}
+
+// alterColumn returns the alterations of the column from into to (i.e. text
+// TYPE varchar(255))
+func alterColumn(from, to Attribute) []string {
+	alterations := make([]string, 0)
+	if !strings.EqualFold(from.Type.ToSQL(), to.Type.ToSQL()) {
+		alterations = append(alterations, fmt.Sprintf("%s TYPE %s", to.Name, to.Type.ToSQL()))
+	}
+
+	if !sameSQL(from.Default, to.Default) {
+		if to.Default == "" {
+			alterations = append(alterations, to.Name+" DROP DEFAULT")
+		} else {
+			alterations = append(alterations, fmt.Sprintf("%s SET DEFAULT %s", to.Name, to.Default))
+		}
+	}
+
+	if from.Nullable != to.Nullable {
+		if to.Nullable {
+			alterations = append(alterations, to.Name+" DROP NOT NULL")
+		} else {
+			alterations = append(alterations, to.Name+" SET NOT NULL")
+		}
+	}
+
+	if from.Identity != to.Identity {
+		switch {
+		case to.Identity == "":
+			alterations = append(alterations, to.Name+" DROP IDENTITY")
+		case from.Identity == "":
+			alterations = append(alterations, fmt.Sprintf("%s ADD GENERATED %s AS IDENTITY", to.Name, to.Identity))
+		default:
+			alterations = append(alterations, fmt.Sprintf("%s SET GENERATED %s", to.Name, to.Identity))
+		}
+	}
+
+	return alterations
+}
+
+// constraint is a named table constraint and its definition
+type constraint struct {
+	name       string
+	definition string
+}
+
+// constraints returns the table constraints of the resource, the primary key
+// named as postgres names it (i.e. setting_pkey)
+func (c CreateTable) constraints() []constraint {
+	constraints := make([]constraint, 0)
+	if primaryKey := c.primaryKeyTemplate(); primaryKey != "" {
+		constraints = append(constraints, constraint{c.TableName + "_pkey", primaryKey})
+	}
+
+	for _, key := range c.ForeignKeys() {
+		constraints = append(constraints, constraint{key.Name, key.ToTemplate()})
+	}
+
+	for _, unique := range c.UniqueConstraints {
+		constraints = append(constraints, constraint{unique.Name, unique.ToTemplate()})
+	}
+
+	for _, check := range c.CheckConstraints {
+		constraints = append(constraints, constraint{check.Name, check.ToTemplate()})
+	}
+
+	return constraints
+}
+
+func findConstraint(constraints []constraint, name string) (string, bool) {
+	for _, constraint := range constraints {
+		if constraint.name == name {
+			return constraint.definition, true
+		}
+	}
+
+	return "", false
+}
+
+// hasIndex returns whether indexes holds an index named as index, of its type
+// and on its columns
+func hasIndex(indexes Indexes, index Index) bool {
+	return indexes.Any(func(other Index) bool {
+		return other.Name == index.Name && strings.EqualFold(other.Type, index.Type) &&
+			strings.Join(other.Columns, ",") == strings.Join(index.Columns, ",")
+	})
+}
+
+func enumsByName(resource Resource) map[string]Enum {
+	enums := map[string]Enum{}
+	for _, enum := range resource.Enums() {
+		enums[enum.Name] = enum
+	}
+
+	return enums
+}
+
+// sameSQL returns whether two SQL fragments only differ by whitespace
+func sameSQL(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
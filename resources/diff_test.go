@@ -0,0 +1,199 @@
+package resources
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+	previous := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "location_id", Type: "UUID"},
+				{Name: "text", Type: "text"},
+				{Name: "sent", Type: "boolean"},
+			},
+			Indexes: Indexes{{Name: "idx_sms_location", Type: "BTREE", Columns: []string{"location_id"}}},
+		},
+	}
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "location_id", Type: "UUID"},
+				{Name: "text", Type: "string(255)", Nullable: true},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}, Default: "'queued'"},
+			},
+			Indexes:          Indexes{{Name: "idx_sms_location", Type: "BTREE", Columns: []string{"location_id", "id"}}},
+			CheckConstraints: []CheckConstraint{{Name: "sms_text_check", Expression: "char_length(text) > 0"}},
+		},
+	}
+
+	Convey("Diff changes every part of the table and reverts it in Down", t, func() {
+		alter, err := Diff(previous, resource)
+		So(err, ShouldBeNil)
+		So(alter, ShouldResemble, AlterTable{
+			TableName: "sms",
+			Up: TableChanges{
+				TableName:      "sms",
+				CreateEnums:    []Enum{{Name: "sms_status", Values: []string{"queued", "sent"}}},
+				DropIndexes:    []string{"idx_sms_location"},
+				AddColumns:     []string{"status sms_status NOT NULL DEFAULT 'queued'"},
+				AlterColumns:   []string{"text TYPE varchar(255)", "text DROP NOT NULL"},
+				DropColumns:    []string{"sent"},
+				AddConstraints: []string{"CONSTRAINT sms_text_check CHECK (char_length(text) > 0)"},
+				CreateIndexes:  Indexes{{Name: "idx_sms_location", Type: "BTREE", Columns: []string{"location_id", "id"}}},
+			},
+			Down: TableChanges{
+				TableName:       "sms",
+				DropConstraints: []string{"sms_text_check"},
+				DropIndexes:     []string{"idx_sms_location"},
+				AddColumns:      []string{"sent boolean NOT NULL"},
+				AlterColumns:    []string{"text TYPE text", "text SET NOT NULL"},
+				DropColumns:     []string{"status"},
+				DropEnums:       []Enum{{Name: "sms_status", Values: []string{"queued", "sent"}}},
+				CreateIndexes:   Indexes{{Name: "idx_sms_location", Type: "BTREE", Columns: []string{"location_id"}}},
+			},
+		})
+	})
+
+	Convey("Diff drops and adds the constraints that changed", t, func() {
+		keyed := resource
+		keyed.PrimaryKey = []string{"location_id", "id"}
+		keyed.Attributes = append(Attributes{}, resource.Attributes...)
+		keyed.Attributes[1].References = &Reference{Table: "location", OnDelete: "CASCADE"}
+
+		alter, err := Diff(resource, keyed)
+		So(err, ShouldBeNil)
+		So(alter.Up.DropConstraints, ShouldResemble, []string{"sms_pkey"})
+		So(alter.Up.AddConstraints, ShouldResemble, []string{
+			"PRIMARY KEY (location_id, id)",
+			"CONSTRAINT sms_location_id_fkey FOREIGN KEY (location_id) REFERENCES location (id) ON DELETE CASCADE",
+		})
+		So(alter.Down.DropConstraints, ShouldResemble, []string{"sms_pkey", "sms_location_id_fkey"})
+		So(alter.Up.CreateIndexes, ShouldBeEmpty)
+	})
+
+	Convey("Diff adds, changes and drops identities", t, func() {
+		sequenced := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "seq", Type: "bigint"},
+			{Name: "position", Type: "integer", Identity: IdentityByDefault},
+		}}}
+		identities := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "seq", Type: "bigint", Identity: IdentityAlways},
+			{Name: "position", Type: "integer", Identity: IdentityAlways},
+		}}}
+
+		alter, err := Diff(sequenced, identities)
+		So(err, ShouldBeNil)
+		So(alter.Up.AlterColumns, ShouldResemble, []string{"seq ADD GENERATED ALWAYS AS IDENTITY", "position SET GENERATED ALWAYS"})
+		So(alter.Down.AlterColumns, ShouldResemble, []string{"seq DROP IDENTITY", "position SET GENERATED BY DEFAULT"})
+	})
+
+	Convey("Diff drops and re-adds a column whose generated expression changed", t, func() {
+		generated := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "text", Type: "text"},
+			{Name: "length", Type: "integer", Generated: "char_length(text)"},
+		}}}
+		changed := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "text", Type: "text"},
+			{Name: "length", Type: "integer", Generated: "octet_length(text)"},
+		}}}
+
+		alter, err := Diff(generated, changed)
+		So(err, ShouldBeNil)
+		So(alter.Up.DropColumns, ShouldResemble, []string{"length"})
+		So(alter.Up.AddColumns, ShouldResemble, []string{"length integer GENERATED ALWAYS AS (octet_length(text)) STORED NOT NULL"})
+		So(alter.Up.AlterColumns, ShouldBeEmpty)
+		So(alter.Down.DropColumns, ShouldResemble, []string{"length"})
+		So(alter.Down.AddColumns, ShouldResemble, []string{"length integer GENERATED ALWAYS AS (char_length(text)) STORED NOT NULL"})
+
+		So(GenerateAlterMigration(generated, changed)[0].Output, ShouldContainSubstring, `ALTER TABLE sms DROP COLUMN length;
+ALTER TABLE sms ADD COLUMN length integer GENERATED ALWAYS AS (octet_length(text)) STORED NOT NULL;`)
+	})
+
+	Convey("Diff drops generated columns before and adds them after the columns they use", t, func() {
+		generated := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "upper_text", Type: "text", Generated: "upper(text)"},
+			{Name: "text", Type: "text"},
+		}}}
+		renamed := Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "body", Type: "text"},
+		}}}
+
+		alter, err := Diff(generated, renamed)
+		So(err, ShouldBeNil)
+		So(alter.Up.DropColumns, ShouldResemble, []string{"upper_text", "text"})
+		So(alter.Down.AddColumns, ShouldResemble, []string{"text text NOT NULL", "upper_text text GENERATED ALWAYS AS (upper(text)) STORED NOT NULL"})
+	})
+
+	Convey("Diff reports changes it cannot generate", t, func() {
+		_, err := Diff(previous, Resource{CreateTable: CreateTable{TableName: "message"}})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `cannot diff table "message" against "sms"`)
+
+		changed := resource
+		changed.Attributes = append(Attributes{}, resource.Attributes...)
+		changed.Attributes[3].Values = []string{"queued", "sent", "failed"}
+		_, err = Diff(resource, changed)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `enum "sms_status" changes values, ALTER TYPE is not generated`)
+	})
+
+	Convey("an unchanged table has nothing to alter", t, func() {
+		alter, err := Diff(resource, resource)
+		So(err, ShouldBeNil)
+		So(alter.Up.Empty(), ShouldBeTrue)
+		So(alter.Down.Empty(), ShouldBeTrue)
+	})
+}
+
+func Test_GenerateAlterMigration(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	previous := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text"},
+				{Name: "sent", Type: "boolean"},
+			},
+		},
+	}
+
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "location_id", Type: "UUID"},
+			},
+			Indexes: Indexes{{Name: "idx_sms_location", Type: "BTREE", Columns: []string{"location_id"}}},
+		},
+	}
+
+	Convey("GenerateAlterMigration writes the changes and their inverse", t, func() {
+		So(GenerateAlterMigration(previous, resource), ShouldResemble, GeneratedGroup{
+			{
+				Output:  goldenFile("generatealtermigration"),
+				FileOut: "20200615120000_alter_sms_table.sql",
+			},
+		})
+	})
+
+	Convey("GenerateAlterMigration fails without changes", t, func() {
+		generated := GenerateAlterMigration(resource, resource)
+		So(generated.AnyErrors(), ShouldBeTrue)
+		So(generated[0].Error.Error(), ShouldEqual, `table "sms" has no changes`)
+	})
+}
@@ -0,0 +1,144 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Enum is a postgres enum type, declared by the attributes with Values
+// (i.e. type sms_status with values queued, sent and failed)
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+// IsEnum returns whether the attribute is an enum, its Type being the enum name
+func (a Attribute) IsEnum() bool {
+	return len(a.Values) > 0
+}
+
+// goEnum returns whether sqlc generates a go type for the enum of the
+// attribute, dialects storing enums as strings having none
+func (a Attribute) goEnum() bool {
+	return a.IsEnum() && Database.GoEnums()
+}
+
+// goAttributeType returns the type the go type of the column derives from,
+// text for enums without a go type
+func (a Attribute) goAttributeType() AttributeType {
+	if a.IsEnum() && !a.goEnum() {
+		return "text"
+	}
+
+	return a.Type
+}
+
+// Enum returns the enum type of the attribute
+func (a Attribute) Enum() Enum {
+	return Enum{
+		Name:   string(a.Type),
+		Values: a.Values,
+	}
+}
+
+// Enums returns the enum types of the resource in attribute order
+func (r Resource) Enums() []Enum {
+	enums := make([]Enum, 0)
+	seen := map[AttributeType]bool{}
+	for _, attr := range r.Attributes {
+		if !attr.IsEnum() || seen[attr.Type] {
+			continue
+		}
+		seen[attr.Type] = true
+
+		enums = append(enums, attr.Enum())
+	}
+
+	return enums
+}
+
+// SQLValues returns the values as a SQL list (i.e. 'queued', 'sent')
+func (e Enum) SQLValues() string {
+	values := make([]string, len(e.Values))
+	for i, value := range e.Values {
+		values[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// ProtoName returns the name of the proto enum (i.e. SmsStatus)
+func (e Enum) ProtoName() string {
+	return strcase.ToCamel(e.Name)
+}
+
+// ProtoValue returns the proto name of value (i.e. SMS_STATUS_QUEUED), the
+// UNSPECIFIED zero value for ""
+func (e Enum) ProtoValue(value string) string {
+	if value == "" {
+		value = "unspecified"
+	}
+
+	return strcase.ToScreamingSnake(identifier(e.Name + "_" + value))
+}
+
+// GoName returns the go type sqlc generates for the enum (i.e. SmsStatus)
+func (e Enum) GoName() string {
+	return strcase.ToCamel(e.Name)
+}
+
+// GoValue returns the go constant sqlc generates for value (i.e. SmsStatusQueued)
+func (e Enum) GoValue(value string) string {
+	return e.GoName() + strcase.ToCamel(identifier(value))
+}
+
+// identifier replaces the characters of s that cannot be part of an identifier
+// (i.e. "didn't send" becomes "didn_t_send")
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, s)
+}
+
+// validateEnum reports enum attributes that would not generate valid types
+func validateEnum(d *Diagnostics, field string, attr Attribute) {
+	if _, ok := LookupType(attr.Type); ok {
+		d.add(field+".type", "enum %q cannot be named after a type", attr.Type)
+	} else if attr.Type == "" {
+		d.add(field+".type", "the enum type name is required")
+	} else {
+		checkName(d, field+".type", string(attr.Type))
+	}
+
+	enum := attr.Enum()
+	values := map[string]bool{}
+	constants := map[string]string{} // the go and proto constants of the values, to the value
+	for i, value := range attr.Values {
+		switch {
+		case value == "":
+			d.add(fmt.Sprintf("%s.values[%d]", field, i), "is required")
+		case values[value]:
+			d.add(fmt.Sprintf("%s.values[%d]", field, i), "duplicate value %q", value)
+		case enum.ProtoValue(value) == enum.ProtoValue(""):
+			d.add(fmt.Sprintf("%s.values[%d]", field, i), "value %q is the proto UNSPECIFIED zero value", value)
+		case constants[enum.GoValue(value)] != "":
+			d.add(fmt.Sprintf("%s.values[%d]", field, i), "value %q generates the constants of %q", value, constants[enum.GoValue(value)])
+		case constants[enum.ProtoValue(value)] != "":
+			d.add(fmt.Sprintf("%s.values[%d]", field, i), "value %q generates the constants of %q", value, constants[enum.ProtoValue(value)])
+		}
+		values[value] = true
+
+		for _, constant := range []string{enum.GoValue(value), enum.ProtoValue(value)} {
+			if constants[constant] == "" {
+				constants[constant] = value
+			}
+		}
+	}
+}
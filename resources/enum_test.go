@@ -0,0 +1,134 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource_enums(t *testing.T) {
+	resource := Resource{
+		Package: "main",
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "status", Type: "sms_status", Values: []string{"queued", "sent", "didn't send"}},
+				{Name: "previous_status", Type: "sms_status", Values: []string{"queued", "sent", "didn't send"}, Nullable: true},
+			},
+		},
+		CrudOptions: []CrudOption{"create"},
+	}
+
+	Convey("Enums lists each enum type once", t, func() {
+		So(resource.Enums(), ShouldResemble, []Enum{
+			{Name: "sms_status", Values: []string{"queued", "sent", "didn't send"}},
+		})
+	})
+
+	Convey("the migration creates the type before the table and drops it after", t, func() {
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldStartWith, "-- +goose Up\n-- +goose StatementBegin\n"+
+			"CREATE TYPE sms_status AS ENUM ('queued', 'sent', 'didn''t send');\n"+
+			"CREATE TABLE IF NOT EXISTS sms\n")
+		So(migration, ShouldContainSubstring, "\tstatus sms_status NOT NULL,\n\tprevious_status sms_status,\n\tPRIMARY KEY (id)\n")
+		So(migration, ShouldEndWith, "DROP TABLE sms;\nDROP TYPE sms_status;\n-- +goose StatementEnd")
+	})
+
+	Convey("the sqlc schema creates the type", t, func() {
+		schema := GenerateSQL(resource)[2].Output
+		So(schema, ShouldStartWith, "\n-- schema.sql\nCREATE TYPE sms_status AS ENUM ('queued', 'sent', 'didn''t send');\nCREATE TABLE sms (")
+	})
+
+	Convey("the proto declares the enum with an UNSPECIFIED zero value", t, func() {
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "enum SmsStatus {\n"+
+			"  SMS_STATUS_UNSPECIFIED = 0;\n"+
+			"  SMS_STATUS_QUEUED = 1;\n"+
+			"  SMS_STATUS_SENT = 2;\n"+
+			"  SMS_STATUS_DIDN_T_SEND = 3;\n"+
+			"}\n")
+		So(proto, ShouldContainSubstring, "  SmsStatus Status = 1;\n")
+	})
+
+	Convey("the tests use a member of the enum", t, func() {
+		tests := GenerateTests(resource)[0].Output
+		So(tests, ShouldContainSubstring, "expectedStatus := SmsStatusQueued\n")
+		So(Enum{Name: "sms_status"}.GoValue("didn't send"), ShouldEqual, "SmsStatusDidnTSend")
+	})
+}
+
+func TestResource_Validate_enums(t *testing.T) {
+	tests := []struct {
+		name string
+		attr Attribute
+		want Diagnostics
+	}{
+		{
+			name: "a valid enum",
+			attr: Attribute{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}},
+			want: Diagnostics{},
+		},
+		{
+			name: "an enum named after a type",
+			attr: Attribute{Name: "status", Type: "text", Values: []string{"queued"}},
+			want: Diagnostics{{Field: "attributes[1].type", Message: `enum "text" cannot be named after a type`}},
+		},
+		{
+			name: "an enum without a name",
+			attr: Attribute{Name: "status", Values: []string{"queued"}},
+			want: Diagnostics{{Field: "attributes[1].type", Message: "the enum type name is required"}},
+		},
+		{
+			name: "empty and duplicate values",
+			attr: Attribute{Name: "status", Type: "sms_status", Values: []string{"queued", "", "queued"}},
+			want: Diagnostics{
+				{Field: "attributes[1].values[1]", Message: "is required"},
+				{Field: "attributes[1].values[2]", Message: `duplicate value "queued"`},
+			},
+		},
+		{
+			name: "a value named like the UNSPECIFIED zero value",
+			attr: Attribute{Name: "status", Type: "sms_status", Values: []string{"queued", "unspecified"}},
+			want: Diagnostics{{Field: "attributes[1].values[1]", Message: `value "unspecified" is the proto UNSPECIFIED zero value`}},
+		},
+		{
+			name: "values generating the same constants",
+			attr: Attribute{Name: "status", Type: "sms_status", Values: []string{"didn't send", "didn_t_send", "Didn't Send"}},
+			want: Diagnostics{
+				{Field: "attributes[1].values[1]", Message: `value "didn_t_send" generates the constants of "didn't send"`},
+				{Field: "attributes[1].values[2]", Message: `value "Didn't Send" generates the constants of "didn't send"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("Validate", t, func() {
+				resource := Resource{
+					CreateTable: CreateTable{
+						TableName:  "sms",
+						Attributes: Attributes{{Name: "id", Type: "UUID"}, tt.attr},
+					},
+				}
+
+				So(resource.Validate(), ShouldResemble, tt.want)
+			})
+		})
+	}
+
+	Convey("Validate reports an enum declared twice with other values", t, func() {
+		resource := Resource{
+			CreateTable: CreateTable{
+				TableName: "sms",
+				Attributes: Attributes{
+					{Name: "status", Type: "sms_status", Values: []string{"queued", "sent"}},
+					{Name: "previous_status", Type: "sms_status", Values: []string{"queued"}},
+				},
+			},
+		}
+
+		So(resource.Validate(), ShouldResemble, Diagnostics{
+			{Field: "attributes[1].values", Message: `enum "sms_status" is declared with other values`},
+		})
+	})
+}
@@ -4,25 +4,32 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/iancoleman/strcase"
 )
 
 const (
 	createTableTemplate = "templates/database/create_table.sql.tmpl"
+	alterTableTemplate  = "templates/database/alter_table.sql.tmpl"
 	grpcMessageTemplate = "templates/grpc/message.proto.tmpl"
 	sqlTemplate         = "templates/database/sqlc.tmpl"
 	sqlYamlTemplate     = "templates/database/sqlc.yaml.tmpl"
 	sqlSchemeTemplate   = "templates/database/sqlc.schema.tmpl"
 	sqlTestTemplate     = "templates/testing/sql.test.tmpl"
+	sqliteTestTemplate  = "templates/testing/sqlite.test.tmpl"
 
-	directory         = "output"
-	templateTimestamp = "{timestamp}"
+	directory = "output"
 )
 
+// TemplateDir is the directory holding goils' templates/ folder. It is checked
+// first so goils can run outside of the repository (i.e. set by -templates)
+var TemplateDir string
+
 func templateFunctions() template.FuncMap {
 	return template.FuncMap{
 		"add": func(a, b int) int {
@@ -55,6 +62,9 @@ func templateFunctions() template.FuncMap {
 
 			return false
 		},
+		"dialect": func() Dialect {
+			return Database
+		},
 	}
 }
 
@@ -73,7 +83,21 @@ func NewTemplate(label string, templateFile string, fileOut string) Template {
 	}
 }
 
+// GenerateTemplates runs templates for resource, every result holds the
+// resource's Diagnostics instead when it is not valid
 func GenerateTemplates(resource Resource, templates ...Template) GeneratedGroup {
+	if err := resource.Validate().Err(); err != nil {
+		generated := make(GeneratedGroup, len(templates))
+		for i, templ := range templates {
+			generated[i] = GeneratedResult{
+				FileOut: templ.FileOut,
+				Error:   err,
+			}
+		}
+
+		return generated
+	}
+
 	return Templates(templates).Run(resource)
 }
 
@@ -92,16 +116,12 @@ func (g GeneratedResult) PrintError() {
 }
 
 func (g GeneratedResult) CreateFile() error {
-	return ioutil.WriteFile(filepath.Join(directory, g.FileOut), []byte(g.Output), 0644)
+	return g.CreateFileIn(directory)
 }
 
-// parseFileOut takes file name string and replaces templates with template values (i.e. date)
-func parseFileOut(fileOut string) string {
-	if strings.Contains(fileOut, templateTimestamp) {
-		return strings.ReplaceAll(fileOut, templateTimestamp, CurrentTime().Format("20060102150405"))
-	}
-
-	return fileOut
+// CreateFileIn writes the generated output into dir
+func (g GeneratedResult) CreateFileIn(dir string) error {
+	return ioutil.WriteFile(filepath.Join(dir, g.FileOut), []byte(g.Output), 0644)
 }
 
 type GeneratedGroup []GeneratedResult
@@ -115,6 +135,21 @@ func (g GeneratedGroup) CreateFiles() {
 	}
 }
 
+// CreateFilesIn writes every generated output into dir, creating it if needed
+func (g GeneratedGroup) CreateFilesIn(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, group := range g {
+		if err := group.CreateFileIn(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (g GeneratedGroup) AnyErrors() bool {
 	for _, group := range g {
 		if group.HasError() {
@@ -156,14 +191,126 @@ func (t Templates) Run(resource Resource) GeneratedGroup {
 	return generated
 }
 
+// GenerateMigration generates the migration creating the table of resource, in
+// the Migrations format
 func GenerateMigration(resource Resource) GeneratedGroup {
-	// TODO: use template system with filename too
-	output := parseFileOut("{timestamp}_create_" + resource.TableName + "_table.sql")
+	return generateCreateMigration(resource, 0, nil)
+}
 
-	return GenerateTemplates(
-		resource,
-		NewTemplate("createTableTemplate", createTableTemplate, output),
-	)
+// GenerateMigrations generates the migrations of every resource ordered by
+// dependency, their versions following each other so parents are created first
+func GenerateMigrations(resources []Resource) (GeneratedGroup, error) {
+	ordered, err := OrderByDependency(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := make(GeneratedGroup, 0, len(ordered))
+	for i, resource := range ordered {
+		generated = append(generated, generateCreateMigration(resource, i, ordered)...)
+	}
+
+	return generated, nil
+}
+
+// generateCreateMigration generates the migration creating the table of
+// resource, sequence being its position among the migrations generated with it
+// and referenced the resources its foreign keys are checked against
+func generateCreateMigration(resource Resource, sequence int, referenced []Resource) GeneratedGroup {
+	name := "create_" + resource.TableName + "_table"
+
+	if err := append(resource.Validate(), resource.ValidateReferenced(referenced)...).Err(); err != nil {
+		return migrationFiles(newMigration(name, sequence), err)
+	}
+
+	return generateMigration(resource, name, sequence, createTableTemplate)
+}
+
+// GenerateAlterMigration generates the migration turning the table of previous
+// into the one of resource (i.e. adding the columns resource added since)
+func GenerateAlterMigration(previous Resource, resource Resource) GeneratedGroup {
+	name := "alter_" + resource.TableName + "_table"
+
+	if err := resource.Validate().Err(); err != nil {
+		return migrationFiles(newMigration(name, 0), err)
+	}
+
+	if err := alterDialect(); err != nil {
+		return migrationFiles(newMigration(name, 0), err)
+	}
+
+	alter, err := Diff(previous, resource)
+	if err == nil && alter.Up.Empty() {
+		err = fmt.Errorf("table %q has no changes", resource.TableName)
+	}
+	if err != nil {
+		return migrationFiles(newMigration(name, 0), err)
+	}
+
+	return generateMigration(alter, name, 0, alterTableTemplate)
+}
+
+// GenerateColumnMigration generates the migration of a targeted column change
+// (i.e. 20200615120000_add_status_to_sms.sql)
+func GenerateColumnMigration(migration ColumnMigration) GeneratedGroup {
+	if err := migration.Resource.Validate().Err(); err != nil {
+		return migrationFiles(newMigration(migration.Name, 0), err)
+	}
+
+	if err := alterDialect(); err != nil {
+		return migrationFiles(newMigration(migration.Name, 0), err)
+	}
+
+	return generateMigration(migration.Alter, migration.Name, 0, alterTableTemplate)
+}
+
+// alterDialect reports the dialects ALTER TABLE migrations are not generated
+// for, their syntax being postgres'
+func alterDialect() error {
+	if _, ok := Database.(Postgres); !ok {
+		return fmt.Errorf("alter migrations are only generated for postgres, not %s", Database.Name())
+	}
+
+	return nil
+}
+
+// newMigration returns the migration named name, versioned a second after the
+// one before it in sequence
+func newMigration(name string, sequence int) Migration {
+	return Migration{
+		Name:     name,
+		Version:  CurrentTime().Add(time.Duration(sequence) * time.Second),
+		Sequence: sequence,
+	}
+}
+
+// generateMigration runs the up and down templates of templateFile for data and
+// lays out the migration named name in the Migrations format
+func generateMigration(data interface{}, name string, sequence int, templateFile string) GeneratedGroup {
+	migration := newMigration(name, sequence)
+
+	up, down, err := executeMigrationTemplate(data, templateFile)
+	if err != nil {
+		return migrationFiles(migration, err)
+	}
+
+	migration.Up = strings.TrimPrefix(up, "\n")
+	migration.Down = strings.TrimPrefix(down, "\n")
+
+	return migrationFiles(migration, nil)
+}
+
+// migrationFiles returns the files of migration, holding err instead of their
+// output when not nil
+func migrationFiles(migration Migration, err error) GeneratedGroup {
+	files := Migrations.Files(migration)
+	if err != nil {
+		for i := range files {
+			files[i] = GeneratedResult{FileOut: files[i].FileOut, Error: err}
+		}
+	}
+
+	return files
 }
 
 func GenerateProto(resource Resource) GeneratedGroup {
@@ -183,32 +330,107 @@ func GenerateSQL(resource Resource) GeneratedGroup {
 	)
 }
 
+// GenerateTests generates the sqlmock tests of the sqlc queries, and for sqlite
+// the tests running them against the schema loaded into an in-process SQLite
 func GenerateTests(resource Resource) GeneratedGroup {
-	return GenerateTemplates(
-		resource,
-		NewTemplate("sqlTestTemplate", sqlTestTemplate, "queries_test.go"),
-	)
+	templates := Templates{NewTemplate("sqlTestTemplate", sqlTestTemplate, "queries_test.go")}
+	if _, ok := Database.(SQLite); ok {
+		templates = append(templates, NewTemplate("sqliteTestTemplate", sqliteTestTemplate, "queries_sqlite_test.go"))
+	}
+
+	return GenerateTemplates(resource, templates...)
 }
 
-func generateStandardTemplate(resource Resource, label string, templateFile string) (string, error) {
+// executeMigrationTemplate runs the up and down templates defined in
+// templateFile for data. A template defining neither is a goose migration
+// written before the other formats, its -- +goose Up and Down blocks are split
+func executeMigrationTemplate(data interface{}, templateFile string) (string, string, error) {
+	temp, err := readTemplate(templateFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	t, err := template.New(templateFile).Funcs(templateFunctions()).Parse(string(temp))
+	if err != nil {
+		return "", "", err
+	}
+
+	if t.Lookup("up") == nil && t.Lookup("down") == nil {
+		buffer := &bytes.Buffer{}
+		if err := t.Execute(buffer, data); err != nil {
+			return "", "", err
+		}
+
+		up, down := splitGooseMigration(buffer.String())
+		return up, down, nil
+	}
+
+	up, down := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := t.ExecuteTemplate(up, "up", data); err != nil {
+		return "", "", err
+	}
+	if err := t.ExecuteTemplate(down, "down", data); err != nil {
+		return "", "", err
+	}
+
+	return up.String(), down.String(), nil
+}
+
+// splitGooseMigration returns the statements of the Up and Down blocks of a
+// goose migration, without its annotations
+func splitGooseMigration(migration string) (string, string) {
+	var up, down []string
+	block := &up
+	for _, line := range strings.Split(migration, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up", "-- +goose StatementBegin", "-- +goose StatementEnd":
+		case "-- +goose Down":
+			block = &down
+		default:
+			*block = append(*block, line)
+		}
+	}
+
+	return strings.Trim(strings.Join(up, "\n"), "\n"), strings.Trim(strings.Join(down, "\n"), "\n")
+}
+
+func generateStandardTemplate(data interface{}, label string, templateFile string) (string, error) {
 	s := ""
 	buffer := bytes.NewBufferString(s)
 
-	temp, err := ioutil.ReadFile(filepath.Join(templateFile))
+	temp, err := readTemplate(templateFile)
 	if err != nil {
-		temp, err = ioutil.ReadFile(filepath.Join("..", templateFile))
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 
 	t := template.Must(
 		template.New(label).Funcs(templateFunctions()).Parse(string(temp)),
 	)
-	err = t.Execute(buffer, resource)
+	err = t.Execute(buffer, data)
 	if err != nil {
 		return "", err
 	}
 
 	return buffer.String(), nil
 }
+
+// readTemplate looks for templateFile in TemplateDir, then relative to the
+// working directory and finally its parent
+func readTemplate(templateFile string) ([]byte, error) {
+	if TemplateDir != "" {
+		temp, err := ioutil.ReadFile(filepath.Join(TemplateDir, templateFile))
+		if err == nil {
+			return temp, nil
+		}
+	}
+
+	temp, err := ioutil.ReadFile(filepath.Join(templateFile))
+	if err != nil {
+		temp, err = ioutil.ReadFile(filepath.Join("..", templateFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return temp, nil
+}
@@ -105,6 +105,7 @@ func Test_GenerateProto(t *testing.T) {
 							},
 						},
 					},
+					Package: "sms",
 					CrudOptions: []CrudOption{
 						"show",
 					},
@@ -252,6 +253,50 @@ func Test_GenerateTests(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "should test NULL columns given nullable attributes",
+			args: args{
+				resource: Resource{
+					Package: "main",
+					CreateTable: CreateTable{
+						TableName: "sms",
+						Attributes: Attributes{
+							{
+								Name:     "id",
+								Type:     "UUID",
+								Nullable: false,
+							},
+							{
+								Name:     "text",
+								Type:     "string",
+								Nullable: true,
+							},
+							{
+								Name:     "created_at",
+								Type:     "date",
+								Nullable: true,
+							},
+							{
+								Name:     "auto",
+								Type:     "boolean",
+								Nullable: false,
+							},
+						},
+					},
+					CrudOptions: []CrudOption{
+						"show",
+						"index",
+						"create",
+					},
+				},
+			},
+			want: GeneratedGroup{
+				GeneratedResult{
+					Output:  goldenFile("generatetestsnullable"),
+					FileOut: "queries_test.go",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
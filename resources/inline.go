@@ -0,0 +1,65 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseAttribute parses the inline attribute syntax "name:type[:null]"
+// (i.e. "created_at:timestamptz:null")
+func ParseAttribute(s string) (Attribute, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Attribute{}, fmt.Errorf("attribute %q: expected name:type[:null]", s)
+	}
+
+	attr := Attribute{
+		Name: parts[0],
+		Type: NormalizeType(parts[1]),
+	}
+
+	for _, modifier := range parts[2:] {
+		switch strings.ToLower(modifier) {
+		case "null", "nullable":
+			attr.Nullable = true
+		default:
+			return attr, fmt.Errorf("attribute %q: unknown modifier %q", s, modifier)
+		}
+	}
+
+	return attr, nil
+}
+
+// ParseIndex parses the inline index syntax "name:[type:]column,column"
+// (i.e. "idx_sms_loc:btree:locationid,id"), the type defaults to BTREE
+func ParseIndex(s string) (Index, error) {
+	parts := strings.Split(s, ":")
+
+	index := Index{
+		Name: parts[0],
+		Type: "BTREE",
+	}
+
+	var columns string
+	switch len(parts) {
+	case 2:
+		columns = parts[1]
+	case 3:
+		index.Type = strings.ToUpper(parts[1])
+		columns = parts[2]
+	default:
+		return index, fmt.Errorf("index %q: expected name:[type:]column,column", s)
+	}
+
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			index.Columns = append(index.Columns, column)
+		}
+	}
+
+	if index.Name == "" || len(index.Columns) == 0 {
+		return index, fmt.Errorf("index %q: expected name:[type:]column,column", s)
+	}
+
+	return index, nil
+}
@@ -0,0 +1,100 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Attribute
+		wantErr bool
+	}{
+		{
+			name: "parses name and type",
+			s:    "text:string",
+			want: Attribute{Name: "text", Type: "string"},
+		},
+		{
+			name: "normalizes uuid",
+			s:    "id:uuid",
+			want: Attribute{Name: "id", Type: "UUID"},
+		},
+		{
+			name: "parses nullable attributes",
+			s:    "created_at:timestamptz:null",
+			want: Attribute{Name: "created_at", Type: "timestamptz", Nullable: true},
+		},
+		{
+			name:    "requires a type",
+			s:       "text",
+			wantErr: true,
+		},
+		{
+			name:    "rejects unknown modifiers",
+			s:       "text:string:unique",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseAttribute", t, func() {
+				got, err := ParseAttribute(tt.s)
+				if tt.wantErr {
+					So(err, ShouldNotBeNil)
+					return
+				}
+
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		})
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Index
+		wantErr bool
+	}{
+		{
+			name: "parses name, type and columns",
+			s:    "idx_sms_loc:btree:locationid,id",
+			want: Index{Name: "idx_sms_loc", Type: "BTREE", Columns: []string{"locationid", "id"}},
+		},
+		{
+			name: "defaults the type to BTREE",
+			s:    "idx_sms_loc:locationid",
+			want: Index{Name: "idx_sms_loc", Type: "BTREE", Columns: []string{"locationid"}},
+		},
+		{
+			name:    "requires columns",
+			s:       "idx_sms_loc",
+			wantErr: true,
+		},
+		{
+			name:    "requires a name",
+			s:       ":btree:id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ParseIndex", t, func() {
+				got, err := ParseIndex(tt.s)
+				if tt.wantErr {
+					So(err, ShouldNotBeNil)
+					return
+				}
+
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// attributeTypeSchema accepts the built in types, with a length or precision
+// (i.e. numeric(12,2)) or as arrays (i.e. UUID[]), and the types a definition registers
+func attributeTypeSchema() map[string]interface{} {
+	types := make([]string, len(builtinTypes))
+	patterns := make([]string, len(builtinTypes))
+	for i, typ := range builtinTypes {
+		types[i] = string(typ)
+		patterns[i] = regexp.QuoteMeta(string(typ))
+	}
+
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": types},
+			map[string]interface{}{"type": "string", "pattern": fmt.Sprintf(`^(%s)(\(\d+(,\s*-?\d+)?\))?(\[\])*$`, strings.Join(patterns, "|"))},
+			map[string]interface{}{"type": "string", "description": "a type registered under types"},
+		},
+	}
+}
+
+func crudOptionSchema() map[string]interface{} {
+	options := make([]string, len(CrudOptions))
+	for i, option := range CrudOptions {
+		options[i] = string(option)
+	}
+
+	return map[string]interface{}{"type": "string", "enum": options}
+}
+
+// JSONSchema returns the JSON Schema of definition files. It is derived from
+// the Definition types (and the built in AttributeTypes and CrudOptions) so it
+// follows whatever the generators accept, definition.schema.json is its output
+func JSONSchema() ([]byte, error) {
+	s := jsonSchemaBuilder{
+		definitions: map[string]interface{}{},
+	}
+
+	schema := map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   "goils resource definition",
+		"description": "A list of resources (or a single resource) goils generates migrations, " +
+			"protos and sqlc queries for",
+		"anyOf": []interface{}{
+			s.schema(reflect.TypeOf(Definition{})),
+			s.schema(reflect.TypeOf(Resource{})),
+		},
+		"definitions": s.definitions,
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+type jsonSchemaBuilder struct {
+	definitions map[string]interface{}
+}
+
+// schema returns the schema of t, structs are added to the definitions and referenced
+func (s jsonSchemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(AttributeType("")):
+		return attributeTypeSchema()
+	case reflect.TypeOf(CrudOption("")):
+		return crudOptionSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := s.definitions[t.Name()]; !ok {
+			s.definitions[t.Name()] = s.object(t)
+		}
+
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": s.schema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": s.schema(t.Elem()),
+		}
+	case reflect.Ptr:
+		return s.schema(t.Elem())
+	}
+
+	return map[string]interface{}{"type": kindName(t)}
+}
+
+// object returns the schema of struct t, fields without omitempty are required
+func (s jsonSchemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := make([]string, 0)
+	s.fields(t, properties, &required)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (s jsonSchemaBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name, inline := yamlTag(sf)
+		if inline {
+			s.fields(sf.Type, properties, required)
+			continue
+		}
+
+		properties[name] = s.schema(sf.Type)
+		if !strings.Contains(sf.Tag.Get("yaml"), ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
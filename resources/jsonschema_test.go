@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONSchema(t *testing.T) {
+	Convey("JSONSchema", t, func() {
+		schema, err := JSONSchema()
+		So(err, ShouldBeNil)
+
+		Convey("matches the shipped definition.schema.json (regenerate with 'goils schema')", func() {
+			shipped, err := ioutil.ReadFile("../definition.schema.json")
+			So(err, ShouldBeNil)
+			So(string(schema), ShouldEqual, string(shipped))
+		})
+
+		Convey("is derived from the resource types", func() {
+			var doc struct {
+				Definitions map[string]struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+					Required   []string                   `json:"required"`
+				} `json:"definitions"`
+			}
+			So(json.Unmarshal(schema, &doc), ShouldBeNil)
+
+			resource := doc.Definitions["Resource"]
+			So(resource.Required, ShouldResemble, []string{"table_name", "attributes"})
+			So(resource.Properties, ShouldContainKey, "owner")
+			So(resource.Properties, ShouldContainKey, "package")
+			So(string(resource.Properties["crud_options"]), ShouldContainSubstring, `"show"`)
+
+			attribute := doc.Definitions["Attribute"]
+			So(attribute.Required, ShouldResemble, []string{"name", "type"})
+			So(string(attribute.Properties["type"]), ShouldContainSubstring, `"timestamptz"`)
+			So(string(attribute.Properties["nullable"]), ShouldContainSubstring, `"boolean"`)
+
+			So(doc.Definitions["Index"].Required, ShouldResemble, []string{"name", "type", "columns"})
+		})
+	})
+}
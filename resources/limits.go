@@ -0,0 +1,168 @@
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxLength    = 10485760 // postgres' limit of varchar(n)
+	maxPrecision = 1000     // postgres' limit of numeric(p,s)
+)
+
+// Defaults are the project wide length and precision of the types declared
+// without any (i.e. string instead of string(255))
+type Defaults struct {
+	Length    int `yaml:"length,omitempty"`    // of string columns, 120 when not set
+	Precision int `yaml:"precision,omitempty"` // of numeric columns, unbounded when not set
+	Scale     int `yaml:"scale,omitempty"`     // of numeric columns given a Precision
+}
+
+// SetDefaults applies defaults to the string and numeric types
+func SetDefaults(defaults Defaults) error {
+	if defaults.Length != 0 {
+		sql := fmt.Sprintf("varchar(%d)", defaults.Length)
+		if err := checkParams(sql); err != nil {
+			return fmt.Errorf("defaults: %v", err)
+		}
+
+		info := typeRegistry["string"]
+		info.SQL = sql
+		typeRegistry["string"] = info
+	}
+
+	if defaults.Precision != 0 || defaults.Scale != 0 {
+		sql := fmt.Sprintf("numeric(%d,%d)", defaults.Precision, defaults.Scale)
+		if err := checkParams(sql); err != nil {
+			return fmt.Errorf("defaults: %v", err)
+		}
+
+		info := typeRegistry["numeric"]
+		info.SQL = sql
+		typeRegistry["numeric"] = info
+	}
+
+	return nil
+}
+
+// stringLength returns the length of string columns (i.e. "120")
+func stringLength() string {
+	_, params, _ := splitParams(typeRegistry["string"].SQL)
+	return params
+}
+
+// checkParams reports a length or precision postgres does not accept in the
+// SQL type (i.e. numeric(2,4))
+func checkParams(sql string) error {
+	base, params, ok := splitParams(strings.TrimRight(sql, "[]"))
+	if !ok {
+		return nil
+	}
+
+	numbers := make([]int, 0, 2)
+	for _, param := range strings.Split(params, ",") {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil // not one of ours, left to postgres
+		}
+		numbers = append(numbers, n)
+	}
+
+	switch base {
+	case "varchar":
+		if numbers[0] < 1 || numbers[0] > maxLength {
+			return fmt.Errorf("the length of %s must be between 1 and %d", sql, maxLength)
+		}
+	case "numeric":
+		if numbers[0] < 1 || numbers[0] > maxPrecision {
+			return fmt.Errorf("the precision of %s must be between 1 and %d", sql, maxPrecision)
+		}
+		if len(numbers) > 1 && (numbers[1] < 0 || numbers[1] > numbers[0]) {
+			return fmt.Errorf("the scale of %s must be between 0 and its precision", sql)
+		}
+	}
+
+	return nil
+}
+
+// Limits describes the length or precision of the column for API consumers
+// (i.e. "max length 255" or "precision 12, scale 2")
+func (a Attribute) Limits() string {
+	base, length, scale := a.limits()
+	switch base {
+	case "varchar":
+		return "max length " + length
+	case "numeric":
+		return fmt.Sprintf("precision %s, scale %s", length, scale)
+	}
+
+	return ""
+}
+
+// ProtoRules returns the protoc-gen-validate rules enforcing the limits of the
+// column when the resource asks for them (i.e. (validate.rules).string.max_len = 255)
+func (r Resource) ProtoRules(attr Attribute) string {
+	if !r.ProtoValidate {
+		return ""
+	}
+
+	base, length, scale := attr.limits()
+	rule := ""
+	switch base {
+	case "varchar":
+		rule = "string.max_len = " + length
+	case "numeric":
+		precision, _ := strconv.Atoi(length)
+		digits, _ := strconv.Atoi(scale)
+		rule = fmt.Sprintf(`string.pattern = "%s"`, decimalPattern(precision, digits))
+	default:
+		return ""
+	}
+
+	if strings.HasSuffix(string(attr.Type), "[]") {
+		rule = "repeated.items." + rule
+	}
+
+	return "(validate.rules)." + rule
+}
+
+// limits returns the base of the column type and its length (or precision) and
+// scale, as declared whatever the dialect (i.e. numeric, 12 and 2)
+func (a Attribute) limits() (string, string, string) {
+	if a.IsEnum() {
+		return "", "", ""
+	}
+
+	info, ok := LookupType(a.Type)
+	if !ok {
+		return "", "", ""
+	}
+
+	base, params, ok := splitParams(strings.TrimRight(info.SQL, "[]"))
+	if !ok {
+		return "", "", ""
+	}
+
+	scale := "0"
+	if i := strings.Index(params, ","); i >= 0 {
+		params, scale = params[:i], params[i+1:]
+	}
+
+	return base, params, scale
+}
+
+// decimalPattern returns the regular expression of the decimal strings a
+// numeric(precision,scale) column stores, escaped for a proto string
+func decimalPattern(precision, scale int) string {
+	integer := fmt.Sprintf(`\\d{1,%d}`, precision-scale)
+	if precision == scale {
+		integer = "0"
+	}
+
+	if scale == 0 {
+		return "^-?" + integer + "$"
+	}
+
+	return fmt.Sprintf(`^-?%s(\\.\\d{1,%d})?$`, integer, scale)
+}
@@ -0,0 +1,123 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttribute_Limits(t *testing.T) {
+	tests := []struct {
+		typ  AttributeType
+		want string
+	}{
+		{typ: "string", want: "max length 120"},
+		{typ: "string(255)", want: "max length 255"},
+		{typ: "varchar(40)[]", want: "max length 40"},
+		{typ: "numeric(12,2)", want: "precision 12, scale 2"},
+		{typ: "numeric(12)", want: "precision 12, scale 0"},
+		{typ: "numeric", want: ""},
+		{typ: "text", want: ""},
+		{typ: "timestamp(3)", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.typ), func(t *testing.T) {
+			Convey("Limits", t, func() {
+				So(Attribute{Name: "value", Type: tt.typ}.Limits(), ShouldEqual, tt.want)
+			})
+		})
+	}
+}
+
+func TestResource_ProtoRules(t *testing.T) {
+	tests := []struct {
+		typ  AttributeType
+		want string
+	}{
+		{typ: "string(255)", want: "(validate.rules).string.max_len = 255"},
+		{typ: "varchar(40)[]", want: "(validate.rules).repeated.items.string.max_len = 40"},
+		{typ: "numeric(12,2)", want: `(validate.rules).string.pattern = "^-?\\d{1,10}(\\.\\d{1,2})?$"`},
+		{typ: "numeric(4)", want: `(validate.rules).string.pattern = "^-?\\d{1,4}$"`},
+		{typ: "numeric(2,2)", want: `(validate.rules).string.pattern = "^-?0(\\.\\d{1,2})?$"`},
+		{typ: "numeric", want: ""},
+		{typ: "text", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.typ), func(t *testing.T) {
+			Convey("ProtoRules", t, func() {
+				resource := Resource{ProtoValidate: true}
+				So(resource.ProtoRules(Attribute{Name: "value", Type: tt.typ}), ShouldEqual, tt.want)
+			})
+		})
+	}
+
+	Convey("the proto enforces the limits with protoc-gen-validate", t, func() {
+		resource := Resource{
+			CreateTable: CreateTable{
+				TableName:  "invoice",
+				Attributes: Attributes{{Name: "id", Type: "UUID"}, {Name: "code", Type: "string(8)"}, {Name: "total", Type: "numeric(12,2)", Nullable: true}},
+			},
+			CrudOptions:   []CrudOption{"create"},
+			ProtoNullable: NullableWrapper,
+		}
+
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldNotContainSubstring, "validate")
+
+		resource.ProtoValidate = true
+		proto = GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "import \"validate/validate.proto\";\n")
+		So(proto, ShouldContainSubstring, "  string Code = 1 [(validate.rules).string.max_len = 8]; // max length 8\n"+
+			"  google.protobuf.StringValue Total = 2 [(validate.rules).string.pattern = \"^-?\\\\d{1,10}(\\\\.\\\\d{1,2})?$\"]; // precision 12, scale 2\n")
+	})
+}
+
+func TestSetDefaults(t *testing.T) {
+	withTypes(t)
+
+	Convey("SetDefaults", t, func() {
+		Convey("sizes the types declared without a length or precision", func() {
+			So(SetDefaults(Defaults{Length: 255, Precision: 12, Scale: 2}), ShouldBeNil)
+			So(AttributeType("string").ToSQL(), ShouldEqual, "varchar(255)")
+			So(AttributeType("string(40)").ToSQL(), ShouldEqual, "varchar(40)")
+			So(AttributeType("numeric").ToSQL(), ShouldEqual, "numeric(12,2)")
+			So(AttributeType("numeric(4)").ToSQL(), ShouldEqual, "numeric(4)")
+			So(NormalizeType("varchar(255)"), ShouldEqual, AttributeType("string"))
+			So(NormalizeType("varchar(120)"), ShouldEqual, AttributeType("varchar(120)"))
+		})
+
+		Convey("are undone by ResetTypes", func() {
+			So(SetDefaults(Defaults{Length: 255, Precision: 12, Scale: 2}), ShouldBeNil)
+
+			ResetTypes()
+			So(AttributeType("string").ToSQL(), ShouldEqual, "varchar(120)")
+			So(AttributeType("numeric").ToSQL(), ShouldEqual, "numeric")
+		})
+
+		Convey("rejects what postgres does not accept", func() {
+			So(SetDefaults(Defaults{Length: -1}), ShouldBeError, "defaults: the length of varchar(-1) must be between 1 and 10485760")
+			So(SetDefaults(Defaults{Precision: 2, Scale: 4}), ShouldBeError, "defaults: the scale of numeric(2,4) must be between 0 and its precision")
+		})
+	})
+}
+
+func TestResource_Validate_limits(t *testing.T) {
+	Convey("Validate reports lengths and precisions postgres does not accept", t, func() {
+		resource := Resource{
+			CreateTable: CreateTable{
+				TableName: "invoice",
+				Attributes: Attributes{
+					{Name: "code", Type: "string(0)"},
+					{Name: "total", Type: "numeric(1001,2)"},
+					{Name: "tax", Type: "numeric(4,6)[]"},
+				},
+			},
+		}
+
+		So(resource.Validate(), ShouldResemble, Diagnostics{
+			{Field: "attributes[0].type", Message: "the length of varchar(0) must be between 1 and 10485760"},
+			{Field: "attributes[1].type", Message: "the precision of numeric(1001,2) must be between 1 and 1000"},
+			{Field: "attributes[2].type", Message: "the scale of numeric(4,6)[] must be between 0 and its precision"},
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration is a generated migration before it is laid out in files by a
+// MigrationFormat
+type Migration struct {
+	Name     string    // describes the migration (i.e. create_sms_table)
+	Version  time.Time // when the migration was generated
+	Sequence int       // position among the migrations generated together, from 0
+	Up       string    // statements applying the migration, one per line
+	Down     string    // statements reverting it
+}
+
+// Timestamp returns the version of the migration as the migration tools
+// order them (i.e. 20200615120000)
+func (m Migration) Timestamp() string {
+	return m.Version.Format("20060102150405")
+}
+
+// MigrationFormat lays out migrations as the files a migration tool runs
+type MigrationFormat interface {
+	Files(migration Migration) GeneratedGroup
+}
+
+// Goose writes a single file with annotated Up and Down sections
+// (i.e. 20200615120000_create_sms_table.sql)
+type Goose struct{}
+
+func (Goose) Files(m Migration) GeneratedGroup {
+	output := "-- +goose Up\n-- +goose StatementBegin\n" + m.Up + "\n-- +goose StatementEnd\n\n" +
+		"-- +goose Down\n-- +goose StatementBegin\n" + m.Down + "\n-- +goose StatementEnd"
+
+	return GeneratedGroup{{Output: output, FileOut: m.Timestamp() + "_" + m.Name + ".sql"}}
+}
+
+// GolangMigrate writes paired up and down files numbered in sequence from
+// Next, 1 when not set (i.e. 000001_create_sms_table.up.sql)
+type GolangMigrate struct {
+	Next int
+}
+
+func (g GolangMigrate) Files(m Migration) GeneratedGroup {
+	next := g.Next
+	if next == 0 {
+		next = 1
+	}
+	prefix := fmt.Sprintf("%06d_%s", next+m.Sequence, m.Name)
+
+	return GeneratedGroup{
+		{Output: m.Up + "\n", FileOut: prefix + ".up.sql"},
+		{Output: m.Down + "\n", FileOut: prefix + ".down.sql"},
+	}
+}
+
+// Dbmate writes a single file with migrate:up and migrate:down sections
+// (i.e. 20200615120000_create_sms_table.sql)
+type Dbmate struct{}
+
+func (Dbmate) Files(m Migration) GeneratedGroup {
+	output := "-- migrate:up\n" + m.Up + "\n\n-- migrate:down\n" + m.Down + "\n"
+
+	return GeneratedGroup{{Output: output, FileOut: m.Timestamp() + "_" + m.Name + ".sql"}}
+}
+
+// Flyway writes a versioned migration and the undo migration reverting it
+// (i.e. V20200615120000__create_sms_table.sql and U20200615120000__create_sms_table.sql)
+type Flyway struct{}
+
+func (Flyway) Files(m Migration) GeneratedGroup {
+	return GeneratedGroup{
+		{Output: m.Up + "\n", FileOut: "V" + m.Timestamp() + "__" + m.Name + ".sql"},
+		{Output: m.Down + "\n", FileOut: "U" + m.Timestamp() + "__" + m.Name + ".sql"},
+	}
+}
+
+// Atlas writes the statements of a versioned migration, Atlas plans the way
+// down itself (i.e. 20200615120000_create_sms_table.sql). The directory's
+// atlas.sum is rewritten with AtlasSum once the migrations are written
+type Atlas struct{}
+
+func (Atlas) Files(m Migration) GeneratedGroup {
+	return GeneratedGroup{{Output: m.Up + "\n", FileOut: m.Timestamp() + "_" + m.Name + ".sql"}}
+}
+
+// AtlasSum returns the atlas.sum of the migrations in dir as atlas migrate
+// hash writes it, the hash of the directory followed by each file and the hash
+// of it and the files before it, so atlas runs the migrations written by goils
+func AtlasSum(dir string) (GeneratedResult, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return GeneratedResult{}, err
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	lines := make([]string, len(files))
+	for i, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return GeneratedResult{}, err
+		}
+
+		hash.Write([]byte(filepath.Base(file)))
+		hash.Write(content)
+		lines[i] = filepath.Base(file) + " h1:" + base64.StdEncoding.EncodeToString(hash.Sum(nil)) + "\n"
+	}
+
+	output := "h1:" + base64.StdEncoding.EncodeToString(hash.Sum(nil)) + "\n" + strings.Join(lines, "")
+
+	return GeneratedResult{Output: output, FileOut: "atlas.sum"}, nil
+}
+
+// MigrationFormats are the formats migrations can be written in, by name
+var MigrationFormats = map[string]MigrationFormat{
+	"goose":          Goose{},
+	"golang-migrate": GolangMigrate{},
+	"dbmate":         Dbmate{},
+	"flyway":         Flyway{},
+	"atlas":          Atlas{},
+}
+
+// Migrations is the format migrations are generated in (i.e. set by -format)
+var Migrations MigrationFormat = Goose{}
+
+// LookupMigrationFormat returns the format named name, goose when name is empty
+func LookupMigrationFormat(name string) (MigrationFormat, error) {
+	if name == "" {
+		return Goose{}, nil
+	}
+
+	format, ok := MigrationFormats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown migration format %q (expected one of %s)", name, strings.Join(MigrationFormatNames(), ", "))
+	}
+
+	return format, nil
+}
+
+// MigrationFormatNames returns the names of the migration formats, sorted
+func MigrationFormatNames() []string {
+	names := make([]string, 0, len(MigrationFormats))
+	for name := range MigrationFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,130 @@
+package resources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMigrationFormats(t *testing.T) {
+	migration := Migration{
+		Name:     "create_sms_table",
+		Version:  time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC),
+		Sequence: 1,
+		Up:       "CREATE TABLE sms (id uuid);",
+		Down:     "DROP TABLE sms;",
+	}
+
+	tests := []struct {
+		name   string
+		format MigrationFormat
+		want   GeneratedGroup
+	}{
+		{
+			name:   "goose",
+			format: Goose{},
+			want: GeneratedGroup{{
+				FileOut: "20200615120000_create_sms_table.sql",
+				Output: "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE sms (id uuid);\n-- +goose StatementEnd\n\n" +
+					"-- +goose Down\n-- +goose StatementBegin\nDROP TABLE sms;\n-- +goose StatementEnd",
+			}},
+		},
+		{
+			name:   "golang-migrate",
+			format: GolangMigrate{Next: 4},
+			want: GeneratedGroup{
+				{FileOut: "000005_create_sms_table.up.sql", Output: "CREATE TABLE sms (id uuid);\n"},
+				{FileOut: "000005_create_sms_table.down.sql", Output: "DROP TABLE sms;\n"},
+			},
+		},
+		{
+			name:   "dbmate",
+			format: Dbmate{},
+			want: GeneratedGroup{{
+				FileOut: "20200615120000_create_sms_table.sql",
+				Output:  "-- migrate:up\nCREATE TABLE sms (id uuid);\n\n-- migrate:down\nDROP TABLE sms;\n",
+			}},
+		},
+		{
+			name:   "flyway",
+			format: Flyway{},
+			want: GeneratedGroup{
+				{FileOut: "V20200615120000__create_sms_table.sql", Output: "CREATE TABLE sms (id uuid);\n"},
+				{FileOut: "U20200615120000__create_sms_table.sql", Output: "DROP TABLE sms;\n"},
+			},
+		},
+		{
+			name:   "atlas",
+			format: Atlas{},
+			want: GeneratedGroup{
+				{FileOut: "20200615120000_create_sms_table.sql", Output: "CREATE TABLE sms (id uuid);\n"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("Files lays out the migration for "+tt.name, t, func() {
+				So(tt.format.Files(migration), ShouldResemble, tt.want)
+			})
+		})
+	}
+
+	Convey("GenerateMigrations numbers golang-migrate migrations in order", t, func() {
+		MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		Migrations = GolangMigrate{}
+		defer func() { Migrations = Goose{} }()
+
+		generated, err := GenerateMigrations([]Resource{
+			{CreateTable: CreateTable{TableName: "location", Attributes: Attributes{{Name: "id", Type: "UUID"}}}},
+			{CreateTable: CreateTable{TableName: "setting", Attributes: Attributes{{Name: "id", Type: "UUID"}}}},
+		})
+		So(err, ShouldBeNil)
+		So(generated.AnyErrors(), ShouldBeFalse)
+		So(generated, ShouldHaveLength, 4)
+		So(generated[2].FileOut, ShouldEqual, "000002_create_setting_table.up.sql")
+		So(generated[2].Output, ShouldStartWith, "CREATE TABLE IF NOT EXISTS setting\n")
+		So(generated[3].Output, ShouldEqual, "DROP TABLE setting;\n")
+	})
+
+	Convey("a create_table template without up and down is split into its goose blocks", t, func() {
+		MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+		TemplateDir = "testdata"
+		Migrations = GolangMigrate{}
+		defer func() { TemplateDir, Migrations = "", Goose{} }()
+
+		generated := GenerateMigration(Resource{CreateTable: CreateTable{TableName: "sms", Attributes: Attributes{{Name: "id", Type: "UUID"}}}})
+		So(generated, ShouldResemble, GeneratedGroup{
+			{FileOut: "000001_create_sms_table.up.sql", Output: "CREATE TABLE IF NOT EXISTS sms\n(\n\tid UUID NOT NULL\n);\n"},
+			{FileOut: "000001_create_sms_table.down.sql", Output: "DROP TABLE IF EXISTS sms;\n"},
+		})
+	})
+
+	Convey("AtlasSum hashes the migrations of the directory as atlas migrate hash", t, func() {
+		dir, err := ioutil.TempDir("", "goils")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(filepath.Join(dir, "20200615120001_create_sms_table.sql"), []byte("CREATE TABLE sms (id uuid);\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "20200615120000_create_location_table.sql"), []byte("CREATE TABLE location (id uuid);\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "atlas.sum"), []byte("h1:outdated\n"), 0644), ShouldBeNil)
+
+		sum, err := AtlasSum(dir)
+		So(err, ShouldBeNil)
+		So(sum, ShouldResemble, GeneratedResult{FileOut: "atlas.sum", Output: "h1:/7ECPN1MK7hLkKSdKr26DpEbJ8+V2Jw20GK53yip9j0=\n" +
+			"20200615120000_create_location_table.sql h1:JoHUKHtZ1LMwpnfmRIUhj7UWtMoDKn7ncCSWdP9V9lY=\n" +
+			"20200615120001_create_sms_table.sql h1:/7ECPN1MK7hLkKSdKr26DpEbJ8+V2Jw20GK53yip9j0=\n"})
+	})
+
+	Convey("LookupMigrationFormat returns goose by default", t, func() {
+		format, err := LookupMigrationFormat("")
+		So(err, ShouldBeNil)
+		So(format, ShouldResemble, Goose{})
+
+		_, err = LookupMigrationFormat("liquibase")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// ProtoNullable options, how nullable columns are generated in protos
+const (
+	NullableOptional = "optional" // proto3 optional fields (the default)
+	NullableWrapper  = "wrapper"  // google.protobuf.*Value wrappers, optional when the type has none
+)
+
+// ProtoNullableOptions are the supported values of Resource.ProtoNullable
+var ProtoNullableOptions = []string{NullableOptional, NullableWrapper}
+
+// ProtoField returns the proto field of the attribute, nullable scalars carry
+// their presence as optional fields or wrappers (message and repeated fields
+// already do)
+func (r Resource) ProtoField(attr Attribute) string {
+	if !attr.Nullable {
+		return attr.ToProto()
+	}
+
+	if wrapper := r.protoWrapper(attr); wrapper != "" {
+		return fmt.Sprintf("%s %s", wrapper, attr.protoName())
+	}
+
+	if proto := attr.Type.ToProto(); !attr.IsEnum() && (strings.HasPrefix(proto, "repeated ") || strings.Contains(proto, ".")) {
+		return attr.ToProto()
+	}
+
+	return "optional " + attr.ToProto()
+}
+
+// protoWrapper returns the google.protobuf.*Value the attribute is generated
+// as, if any
+func (r Resource) protoWrapper(attr Attribute) string {
+	if !attr.Nullable || attr.IsEnum() || r.ProtoNullable != NullableWrapper {
+		return ""
+	}
+
+	info, _ := LookupType(attr.Type)
+
+	return info.ProtoWrapper
+}
+
+// HasNullable returns whether any attribute is nullable
+func (r Resource) HasNullable() bool {
+	return r.Attributes.Any(func(attr Attribute) bool {
+		return attr.Nullable
+	})
+}
+
+// goNull returns the type sqlc generates for the nullable column and its value
+// field (i.e. sql.NullString and String), none when the zero value of the go
+// type stands for NULL (i.e. []byte)
+func (a Attribute) goNull() (string, string) {
+	if !a.Nullable {
+		return "", ""
+	}
+
+	if a.goEnum() {
+		return "Null" + a.Enum().GoName(), a.Enum().GoName()
+	}
+
+	info := a.goAttributeType().goInfo()
+	if info.GoNull == "" {
+		return "", ""
+	}
+
+	name := info.GoNull[strings.LastIndex(info.GoNull, ".")+1:]
+
+	return info.GoNull, strings.TrimPrefix(name, "Null")
+}
+
+// GoName returns the struct field sqlc generates for the column (i.e. LocationID)
+func (a Attribute) GoName() string {
+	parts := strings.Split(a.Name, "_")
+	for i, part := range parts {
+		if part == "id" {
+			parts[i] = "ID"
+			continue
+		}
+		parts[i] = strcase.ToCamel(part)
+	}
+
+	return strings.Join(parts, "")
+}
+
+// TestVar returns the variable generated tests hold the column value in
+func (a Attribute) TestVar() string {
+	return "expected" + strcase.ToCamel(a.Name)
+}
+
+// TestWant returns the go value sqlc scans TestVar into, wrapped in the null
+// type of nullable columns (i.e. sql.NullString{String: expectedText, Valid: true})
+func (a Attribute) TestWant() string {
+	null, field := a.goNull()
+	if null == "" {
+		return a.TestVar()
+	}
+
+	value := a.TestVar()
+	if goType := a.Type.ToGo(); !a.IsEnum() && !strings.ContainsAny(goType, ".[]") && strings.ToLower(field) != goType {
+		value = fmt.Sprintf("%s(%s)", strings.ToLower(field), value) // i.e. float64 for real
+	}
+
+	return fmt.Sprintf("%s{%s: %s, Valid: true}", null, field, value)
+}
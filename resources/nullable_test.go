@@ -0,0 +1,139 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource_ProtoField(t *testing.T) {
+	status := Attribute{Name: "status", Type: "sms_status", Values: []string{"queued"}, Nullable: true}
+
+	tests := []struct {
+		name     string
+		nullable string
+		attr     Attribute
+		want     string
+	}{
+		{
+			name: "a column that is not nullable",
+			attr: Attribute{Name: "text", Type: "text"},
+			want: "string Text",
+		},
+		{
+			name: "a nullable scalar is optional by default",
+			attr: Attribute{Name: "text", Type: "text", Nullable: true},
+			want: "optional string Text",
+		},
+		{
+			name:     "a nullable scalar given wrappers",
+			nullable: NullableWrapper,
+			attr:     Attribute{Name: "count", Type: "integer", Nullable: true},
+			want:     "google.protobuf.Int32Value Count",
+		},
+		{
+			name:     "a nullable message already has presence",
+			nullable: NullableWrapper,
+			attr:     Attribute{Name: "sent_at", Type: "timestamptz", Nullable: true},
+			want:     "google.protobuf.Timestamp SentAt",
+		},
+		{
+			name: "a nullable array already has presence",
+			attr: Attribute{Name: "tags", Type: "text[]", Nullable: true},
+			want: "repeated string Tags",
+		},
+		{
+			name:     "a nullable enum is optional given wrappers",
+			nullable: NullableWrapper,
+			attr:     status,
+			want:     "optional SmsStatus Status",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("ProtoField", t, func() {
+				So(Resource{ProtoNullable: tt.nullable}.ProtoField(tt.attr), ShouldEqual, tt.want)
+			})
+		})
+	}
+}
+
+func TestAttribute_nullable_go(t *testing.T) {
+	tests := []struct {
+		attr     Attribute
+		wantType string
+		wantTest string
+	}{
+		{
+			attr:     Attribute{Name: "text", Type: "string"},
+			wantType: "string",
+			wantTest: "expectedText",
+		},
+		{
+			attr:     Attribute{Name: "text", Type: "string", Nullable: true},
+			wantType: "sql.NullString",
+			wantTest: "sql.NullString{String: expectedText, Valid: true}",
+		},
+		{
+			attr:     Attribute{Name: "location_id", Type: "UUID", Nullable: true},
+			wantType: "uuid.NullUUID",
+			wantTest: "uuid.NullUUID{UUID: expectedLocationId, Valid: true}",
+		},
+		{
+			attr:     Attribute{Name: "ratio", Type: "real", Nullable: true},
+			wantType: "sql.NullFloat64",
+			wantTest: "sql.NullFloat64{Float64: float64(expectedRatio), Valid: true}",
+		},
+		{
+			attr:     Attribute{Name: "status", Type: "sms_status", Values: []string{"queued"}, Nullable: true},
+			wantType: "NullSmsStatus",
+			wantTest: "NullSmsStatus{SmsStatus: expectedStatus, Valid: true}",
+		},
+		{
+			attr:     Attribute{Name: "payload", Type: "bytea", Nullable: true},
+			wantType: "[]byte",
+			wantTest: "expectedPayload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.attr.Type), func(t *testing.T) {
+			Convey("GoType and TestWant", t, func() {
+				So(tt.attr.GoType(), ShouldEqual, tt.wantType)
+				So(tt.attr.TestWant(), ShouldEqual, tt.wantTest)
+			})
+		})
+	}
+}
+
+func TestResource_nullable_imports(t *testing.T) {
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "sms",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "text", Type: "text", Nullable: true},
+				{Name: "sent_at", Type: "timestamptz", Nullable: true},
+			},
+		},
+		CrudOptions:   []CrudOption{"create"},
+		ProtoNullable: NullableWrapper,
+	}
+
+	Convey("wrappers are imported by the proto", t, func() {
+		So(resource.ProtoImports(), ShouldResemble, []string{
+			"google/protobuf/timestamp.proto",
+			"google/protobuf/wrappers.proto",
+		})
+	})
+
+	Convey("the null types are imported by the tests", t, func() {
+		So(resource.TestStdImports(), ShouldResemble, []string{"context", "database/sql", "testing", "time"})
+	})
+
+	Convey("an unknown option is reported", t, func() {
+		resource.ProtoNullable = "pointer"
+		So(resource.Validate(), ShouldResemble, Diagnostics{
+			{Field: "proto_nullable", Message: `unknown option "pointer" (expected one of optional, wrapper)`},
+		})
+	})
+}
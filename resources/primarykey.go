@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Key returns the columns identifying a row, the declared PrimaryKey or the
+// implicit id column of resources declaring none
+func (c CreateTable) Key() []string {
+	if len(c.PrimaryKey) > 0 {
+		return c.PrimaryKey
+	}
+
+	if indexOfAttribute(c.Attributes, "id") >= 0 {
+		return []string{"id"}
+	}
+
+	return nil
+}
+
+// KeyAttributes returns the attributes of Key, in key order
+func (c CreateTable) KeyAttributes() Attributes {
+	attrs := make(Attributes, 0, len(c.Key()))
+	for _, name := range c.Key() {
+		if i := indexOfAttribute(c.Attributes, name); i >= 0 {
+			attrs = append(attrs, c.Attributes[i])
+		}
+	}
+
+	return attrs
+}
+
+// IsKey returns whether the column named name is part of Key
+func (c CreateTable) IsKey(name string) bool {
+	return indexOf(c.Key(), name) >= 0
+}
+
+// primaryKeyTemplate returns the PRIMARY KEY constraint of Key, the implicit id
+// column too so that foreign keys have a key to reference
+func (c CreateTable) primaryKeyTemplate() string {
+	key := c.Key()
+	if len(key) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(key, ", "))
+}
+
+// IsUnique returns whether the columns are the Key or a unique constraint of
+// the table, the only columns a foreign key can reference
+func (c CreateTable) IsUnique(columns []string) bool {
+	if sameColumns(c.Key(), columns) {
+		return true
+	}
+
+	for _, unique := range c.UniqueConstraints {
+		if sameColumns(unique.Columns, columns) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, column := range a {
+		if indexOf(b, column) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ArgName returns the name of the argument holding the column value in
+// generated tests (i.e. locationId)
+func (a Attribute) ArgName() string {
+	return strcase.ToLowerCamel(a.Name)
+}
+
+// validatePrimaryKey reports key columns that are not attributes, repeated or
+// nullable
+func (c CreateTable) validatePrimaryKey(d *Diagnostics) {
+	seen := map[string]bool{}
+	for i, column := range c.PrimaryKey {
+		field := fmt.Sprintf("primary_key[%d]", i)
+
+		j := indexOfAttribute(c.Attributes, column)
+		switch {
+		case seen[column]:
+			d.add(field, "duplicate column %q", column)
+		case j < 0:
+			d.add(field, "%q is not an attribute", column)
+		case c.Attributes[j].Nullable:
+			d.add(field, "%q is nullable, primary key columns cannot be", column)
+		}
+		seen[column] = true
+	}
+}
+
+func indexOfAttribute(attributes Attributes, name string) int {
+	for i, attr := range attributes {
+		if attr.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,57 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateTable_primaryKey(t *testing.T) {
+	resource := Resource{
+		Package: "main",
+		CreateTable: CreateTable{
+			TableName: "setting",
+			Attributes: Attributes{
+				{Name: "location_id", Type: "UUID"},
+				{Name: "name", Type: "text"},
+				{Name: "value", Type: "text"},
+			},
+			PrimaryKey: []string{"location_id", "name"},
+		},
+		CrudOptions: []CrudOption{"show", "index", "create"},
+	}
+
+	Convey("the migration declares the primary key", t, func() {
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldContainSubstring, "\tvalue text NOT NULL,\n\tPRIMARY KEY (location_id, name)\n)")
+	})
+
+	Convey("show finds the row by the key and create gives it", t, func() {
+		queries := GenerateSQL(resource)[0].Output
+		So(queries, ShouldContainSubstring, "SELECT * FROM setting\nWHERE location_id = $1 AND name = $2 LIMIT 1;")
+		So(queries, ShouldContainSubstring, "INSERT INTO setting (\n    location_id,\n    name,\n    value\n)")
+	})
+
+	Convey("the show message is the key and the list message leaves it out", t, func() {
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "message Setting {\n  shared.UUID LocationId = 1;\n  string Name = 2;\n}")
+		So(proto, ShouldContainSubstring, "message ListSetting {\n  string Value = 1;\n}")
+	})
+
+	Convey("the show test passes the key as sqlc params", t, func() {
+		tests := GenerateTests(resource)[0].Output
+		So(tests, ShouldContainSubstring, "\t\tlocationId uuid.UUID\n\t\tname string\n")
+		So(tests, ShouldContainSubstring, "\t\t\tname: \"returns requested Setting given location_id and name\",\n")
+		So(tests, ShouldContainSubstring, "pg.GetSetting(tt.args.ctx, GetSettingParams{LocationID: tt.args.locationId, Name: tt.args.name})")
+	})
+
+	Convey("an undeclared primary key is the id column, still declared as the primary key", t, func() {
+		table := CreateTable{Attributes: Attributes{{Name: "text", Type: "text"}, {Name: "id", Type: "UUID"}}}
+		So(table.Key(), ShouldResemble, []string{"id"})
+		So(table.Elements(), ShouldResemble, []string{"text text NOT NULL", "id UUID NOT NULL", "PRIMARY KEY (id)"})
+
+		table.Attributes = table.Attributes[:1]
+		So(table.Key(), ShouldBeNil)
+		So(table.Elements(), ShouldResemble, []string{"text text NOT NULL"})
+	})
+}
@@ -0,0 +1,224 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	. "weavelab.xyz/goils/extstring"
+)
+
+// ReferentialActions are the ON DELETE and ON UPDATE actions of a foreign key
+var ReferentialActions = []string{"CASCADE", "RESTRICT", "NO ACTION", "SET NULL", "SET DEFAULT"}
+
+// Reference makes the attribute a foreign key to the column of another
+// resource's table, the resource belonging to that row (i.e. locationid
+// references location.id)
+type Reference struct {
+	Table    string `yaml:"table"`
+	Column   string `yaml:"column,omitempty"`    // id when not set
+	OnDelete string `yaml:"on_delete,omitempty"` // i.e. CASCADE or SET NULL
+	OnUpdate string `yaml:"on_update,omitempty"`
+}
+
+// ReferencedColumn returns the column of Table the reference points at
+func (r Reference) ReferencedColumn() string {
+	if r.Column == "" {
+		return "id"
+	}
+
+	return r.Column
+}
+
+// ForeignKey is a FOREIGN KEY constraint of the table, declared by an
+// attribute with References
+type ForeignKey struct {
+	Name      string
+	Column    string
+	Reference Reference
+}
+
+func (f ForeignKey) ToTemplate() string {
+	constraint := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		f.Name, f.Column, f.Reference.Table, f.Reference.ReferencedColumn())
+
+	if f.Reference.OnDelete != "" {
+		constraint += " ON DELETE " + strings.ToUpper(f.Reference.OnDelete)
+	}
+
+	if f.Reference.OnUpdate != "" {
+		constraint += " ON UPDATE " + strings.ToUpper(f.Reference.OnUpdate)
+	}
+
+	return constraint
+}
+
+// ForeignKeys returns the foreign keys of the attributes with References, named
+// as postgres names them (i.e. sms_locationid_fkey)
+func (c CreateTable) ForeignKeys() []ForeignKey {
+	keys := make([]ForeignKey, 0)
+	for _, attr := range c.Attributes {
+		if attr.References == nil {
+			continue
+		}
+
+		keys = append(keys, ForeignKey{
+			Name:      fmt.Sprintf("%s_%s_fkey", c.TableName, attr.Name),
+			Column:    attr.Name,
+			Reference: *attr.References,
+		})
+	}
+
+	return keys
+}
+
+// AllIndexes returns the indexes of the table and the ones supporting its
+// foreign keys, foreign keys leading an index or the primary key already have one
+func (c CreateTable) AllIndexes() Indexes {
+	indexes := append(Indexes{}, c.Indexes...)
+	for _, key := range c.ForeignKeys() {
+		covered := c.Indexes.Any(func(index Index) bool {
+			return len(index.Columns) > 0 && index.Columns[0] == key.Column
+		})
+		if covered || (len(c.Key()) > 0 && c.Key()[0] == key.Column) {
+			continue
+		}
+
+		indexes = append(indexes, Index{
+			Name:    fmt.Sprintf("idx_%s_%s", c.TableName, key.Column),
+			Type:    "BTREE",
+			Columns: []string{key.Column},
+		})
+	}
+
+	return indexes
+}
+
+// ParentName returns the name of the resource a foreign key attribute belongs
+// to, its column without the id suffix (i.e. Location for locationid)
+func (a Attribute) ParentName() string {
+	name := strings.TrimSuffix(strings.TrimSuffix(a.Name, "id"), "_")
+	if name == "" {
+		name = a.References.Table
+	}
+
+	return strcase.ToCamel(name)
+}
+
+// newIndexByProtoMessage lists the rows belonging to the parent attr references
+// (i.e. ListSettingsByLocation)
+func newIndexByProtoMessage(resource Resource, attr Attribute) ProtoMessage {
+	model := strcase.ToCamel(resource.TableName)
+
+	return ProtoMessage{
+		Type:       "index",
+		Name:       fmt.Sprintf("List%sBy%s", ExtString(model).Pluralize(), attr.ParentName()),
+		ModelName:  model,
+		Attributes: []Attribute{attr},
+		Scope:      []Attribute{attr},
+	}
+}
+
+// validateReferences reports references without a table and unknown actions
+func (c CreateTable) validateReferences(d *Diagnostics) {
+	for i, attr := range c.Attributes {
+		if attr.References == nil {
+			continue
+		}
+		field := fmt.Sprintf("attributes[%d].references", i)
+
+		if attr.References.Table == "" {
+			d.add(field+".table", "is required")
+		}
+
+		actions := map[string]string{"on_delete": attr.References.OnDelete, "on_update": attr.References.OnUpdate}
+		for _, name := range []string{"on_delete", "on_update"} {
+			action := strings.ToUpper(actions[name])
+			switch {
+			case action == "":
+			case indexOf(ReferentialActions, action) < 0:
+				d.add(field+"."+name, "unknown action %q (expected one of %s)", actions[name], strings.Join(ReferentialActions, ", "))
+			case action == "SET NULL" && !attr.Nullable:
+				d.add(field+"."+name, "SET NULL needs a nullable column")
+			}
+		}
+	}
+}
+
+// ValidateReferenced reports the references to a column of resources that is
+// neither their key nor unique, which the databases reject a foreign key to.
+// References to tables that are not among resources are left to the database
+func (r Resource) ValidateReferenced(resources []Resource) Diagnostics {
+	d := Diagnostics{}
+	for i, attr := range r.Attributes {
+		if attr.References == nil {
+			continue
+		}
+
+		for _, referenced := range resources {
+			column := attr.References.ReferencedColumn()
+			if referenced.TableName == attr.References.Table && !referenced.IsUnique([]string{column}) {
+				d.add(fmt.Sprintf("attributes[%d].references", i), "%s.%s is neither the primary key nor a unique constraint of %s",
+					referenced.TableName, column, referenced.TableName)
+			}
+		}
+	}
+
+	return d
+}
+
+// OrderByDependency returns the resources with every resource after the ones
+// it references, resources keep their order otherwise. References to tables
+// that are not defined are left to the database
+func OrderByDependency(resources []Resource) ([]Resource, error) {
+	defined := map[string]int{}
+	for i, resource := range resources {
+		defined[resource.TableName] = i
+	}
+
+	ordered := make([]Resource, 0, len(resources))
+	state := make([]int, len(resources)) // 0 not visited, 1 visiting, 2 done
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		path = append(path, resources[i].TableName)
+		switch state[i] {
+		case 1:
+			return fmt.Errorf("resources reference each other: %s", strings.Join(path, " -> "))
+		case 2:
+			return nil
+		}
+		state[i] = 1
+
+		for _, parent := range resources[i].parents() {
+			if j, ok := defined[parent]; ok && j != i {
+				if err := visit(j, path); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[i] = 2
+		ordered = append(ordered, resources[i])
+
+		return nil
+	}
+
+	for i := range resources {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// parents returns the tables the resource references
+func (r Resource) parents() []string {
+	tables := make([]string, 0)
+	for _, key := range r.ForeignKeys() {
+		tables = appendUnique(tables, key.Reference.Table)
+	}
+
+	return tables
+}
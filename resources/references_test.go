@@ -0,0 +1,181 @@
+package resources
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateTable_references(t *testing.T) {
+	resource := Resource{
+		Package: "main",
+		CreateTable: CreateTable{
+			TableName: "setting",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "locationid", Type: "UUID", References: &Reference{Table: "location", OnDelete: "cascade"}},
+				{Name: "name", Type: "text"},
+			},
+		},
+		CrudOptions: []CrudOption{"index"},
+	}
+
+	Convey("the migration adds the foreign key, its index and drops it in Down", t, func() {
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldContainSubstring, "\tname text NOT NULL,\n"+
+			"\tPRIMARY KEY (id),\n"+
+			"\tCONSTRAINT setting_locationid_fkey FOREIGN KEY (locationid) REFERENCES location (id) ON DELETE CASCADE\n)")
+		So(migration, ShouldContainSubstring, "CREATE INDEX IF NOT EXISTS idx_setting_locationid\n\tON setting\n\tUSING BTREE\n(locationid);")
+		So(migration, ShouldContainSubstring, "ALTER TABLE setting DROP CONSTRAINT IF EXISTS setting_locationid_fkey;\n")
+	})
+
+	Convey("an index led by the foreign key supports it", t, func() {
+		indexed := resource
+		indexed.Indexes = Indexes{{Name: "idx_setting_location", Type: "BTREE", Columns: []string{"locationid", "name"}}}
+		So(indexed.AllIndexes(), ShouldResemble, indexed.Indexes)
+	})
+
+	Convey("index lists the settings of a location", t, func() {
+		queries := GenerateSQL(resource)[0].Output
+		So(queries, ShouldContainSubstring, "-- name: ListSettingsByLocation :many\nSELECT * FROM setting\nWHERE locationid = $1;")
+
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "message ListSettingsByLocation {\n  shared.UUID LocationID = 1;\n}")
+
+		tests := GenerateTests(resource)[0].Output
+		So(tests, ShouldContainSubstring, "\t\t\tname: \"returns list of Settings given locationid\",\n")
+		So(tests, ShouldContainSubstring, "pg.ListSettingsByLocation(tt.args.ctx, tt.args.locationid)")
+	})
+
+	Convey("Validate reports references without a table and unknown actions", t, func() {
+		invalid := resource
+		invalid.Attributes = Attributes{
+			{Name: "id", Type: "UUID", References: &Reference{OnUpdate: "delete"}},
+			{Name: "locationid", Type: "UUID", References: &Reference{Table: "location", OnDelete: "set null"}},
+		}
+
+		So(invalid.Validate(), ShouldResemble, Diagnostics{
+			{Field: "attributes[0].references.table", Message: "is required"},
+			{Field: "attributes[0].references.on_update", Message: `unknown action "delete" (expected one of CASCADE, RESTRICT, NO ACTION, SET NULL, SET DEFAULT)`},
+			{Field: "attributes[1].references.on_delete", Message: "SET NULL needs a nullable column"},
+		})
+	})
+
+	Convey("ValidateReferenced reports references to a column that is neither key nor unique", t, func() {
+		location := Resource{CreateTable: CreateTable{TableName: "location", Attributes: Attributes{
+			{Name: "id", Type: "UUID"},
+			{Name: "code", Type: "text"},
+		}}}
+		So(resource.ValidateReferenced([]Resource{location}), ShouldBeEmpty)
+
+		coded := resource
+		coded.Attributes = Attributes{{Name: "locationcode", Type: "text", References: &Reference{Table: "location", Column: "code"}}}
+		So(coded.ValidateReferenced([]Resource{location}), ShouldResemble, Diagnostics{
+			{Field: "attributes[0].references", Message: "location.code is neither the primary key nor a unique constraint of location"},
+		})
+
+		location.UniqueConstraints = []UniqueConstraint{{Name: "location_code_key", Columns: []string{"code"}}}
+		So(coded.ValidateReferenced([]Resource{location}), ShouldBeEmpty)
+
+		location.PrimaryKey = []string{"code", "id"}
+		So(resource.ValidateReferenced([]Resource{location}), ShouldHaveLength, 1)
+	})
+}
+
+func TestGenerateMigrations(t *testing.T) {
+	MockedTime = time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	table := func(name string, parents ...string) Resource {
+		attributes := Attributes{{Name: "id", Type: "UUID"}}
+		for _, parent := range parents {
+			attributes = append(attributes, Attribute{Name: parent + "_id", Type: "UUID", References: &Reference{Table: parent}})
+		}
+
+		return Resource{CreateTable: CreateTable{TableName: name, Attributes: attributes}}
+	}
+
+	Convey("referenced tables are created first", t, func() {
+		generated, err := GenerateMigrations([]Resource{
+			table("setting", "location", "account"),
+			table("location", "account"),
+			table("sms", "external"),
+			table("account"),
+		})
+		So(err, ShouldBeNil)
+
+		files := make([]string, len(generated))
+		for i, result := range generated {
+			files[i] = result.FileOut
+		}
+		So(files, ShouldResemble, []string{
+			"20200615120000_create_account_table.sql",
+			"20200615120001_create_location_table.sql",
+			"20200615120002_create_setting_table.sql",
+			"20200615120003_create_sms_table.sql",
+		})
+	})
+
+	Convey("a reference to a column that is not unique fails its migration", t, func() {
+		generated, err := GenerateMigrations([]Resource{table("setting", "location"), table("location")})
+		So(err, ShouldBeNil)
+		So(generated[0].Error, ShouldBeNil)
+		So(generated[0].Output, ShouldContainSubstring, "\tPRIMARY KEY (id)\n")
+
+		setting := table("setting", "location")
+		setting.Attributes[1].References.Column = "location_id"
+		generated, err = GenerateMigrations([]Resource{setting, table("location")})
+		So(err, ShouldBeNil)
+		So(generated[1].Error, ShouldNotBeNil)
+		So(generated[1].Error.Error(), ShouldContainSubstring, "location.location_id is neither the primary key nor a unique constraint of location")
+	})
+
+	Convey("the foreign keys of the migrations hold in sqlite", t, func() {
+		sqlite3, err := exec.LookPath("sqlite3")
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+
+		Database, Migrations = SQLite{}, GolangMigrate{}
+		defer func() { Database, Migrations = Postgres{}, Goose{} }()
+
+		definition := Definition{Resources: []Resource{
+			table("setting", "location"),
+			{CreateTable: CreateTable{TableName: "location", Attributes: Attributes{{Name: "id", Type: "UUID"}}}, HasManyThrough: []HasManyThrough{{Table: "tag"}}},
+			table("tag"),
+		}}
+		joins, err := definition.JoinResources()
+		So(err, ShouldBeNil)
+
+		generated, err := GenerateMigrations(append(definition.Resources, joins...))
+		So(err, ShouldBeNil)
+		So(generated.AnyErrors(), ShouldBeFalse)
+
+		script := "PRAGMA foreign_keys = ON;\n"
+		for _, result := range generated {
+			if strings.HasSuffix(result.FileOut, ".up.sql") {
+				script += result.Output
+			}
+		}
+		script += "INSERT INTO location (id) VALUES ('l');\n" +
+			"INSERT INTO tag (id) VALUES ('t');\n" +
+			"INSERT INTO setting (id, location_id) VALUES ('s', 'l');\n" +
+			"INSERT INTO location_tag (location_id, tag_id) VALUES ('l', 't');\n" +
+			"SELECT count(*) FROM location_tag;\n"
+
+		cmd := exec.Command(sqlite3, "-bail", ":memory:")
+		cmd.Stdin = strings.NewReader(script)
+		out, err := cmd.CombinedOutput()
+		So(string(out), ShouldEqual, "1\n")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("tables referencing each other cannot be ordered", t, func() {
+		_, err := GenerateMigrations([]Resource{table("location", "account"), table("account", "location")})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "resources reference each other: location -> account -> location")
+	})
+}
@@ -2,6 +2,8 @@ package resources
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
@@ -11,46 +13,139 @@ import (
 
 type AttributeType string
 
+// ToProto returns the proto field type of the column (i.e. google.protobuf.Timestamp)
 func (a AttributeType) ToProto() string {
-	switch a {
-	case "UUID":
-		return "shared.UUID"
-	}
-	return ""
+	info, _ := LookupType(a)
+	return info.Proto
 }
 
+// ToSQL returns the SQL type of the column in the Database dialect,
+// unregistered types are used as is
 func (a AttributeType) ToSQL() string {
-	switch a {
-	case "string":
-		return "varchar(120)"
-	default:
-		return string(a)
+	if info, ok := LookupType(a); ok {
+		return Database.ColumnType(info)
 	}
+
+	return string(a)
+}
+
+// ToGo returns the go type sqlc generates for the column (i.e. time.Time)
+func (a AttributeType) ToGo() string {
+	return a.goInfo().Go
+}
+
+// TestValue returns a go expression of a value of the column for generated tests
+func (a AttributeType) TestValue() string {
+	return a.goInfo().TestValue
+}
+
+// goInfo returns the registered type with the go types sqlc generates for its
+// column in the Database dialect
+func (a AttributeType) goInfo() TypeInfo {
+	info, _ := LookupType(a)
+	return Database.GoType(info)
 }
 
 type Attribute struct {
-	Name     string
-	Type     AttributeType
-	Nullable bool
+	Name       string        `yaml:"name"`
+	Type       AttributeType `yaml:"type"` // the enum type name when Values are given
+	Nullable   bool          `yaml:"nullable,omitempty"`
+	Values     []string      `yaml:"values,omitempty"`     // makes the attribute an enum (i.e. [queued, sent])
+	Default    string        `yaml:"default,omitempty"`    // SQL literal or expression (i.e. now() or 'queued')
+	Generated  string        `yaml:"generated,omitempty"`  // expression of a GENERATED ALWAYS AS (...) STORED column
+	Identity   string        `yaml:"identity,omitempty"`   // ALWAYS or BY DEFAULT, makes the column GENERATED ... AS IDENTITY
+	Create     bool          `yaml:"create,omitempty"`     // keeps a defaulted column in the create query and message
+	References *Reference    `yaml:"references,omitempty"` // makes the column a foreign key (i.e. to location.id)
 }
 
 func (a Attribute) ToTemplate() string {
-	nullableStr := ""
+	// mysql takes the generated expression before the other column attributes
+	constraints := ""
+	if a.Generated != "" {
+		constraints += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", a.Generated)
+	}
+
 	if !a.Nullable {
-		nullableStr = " NOT NULL"
+		constraints += " NOT NULL"
+	}
+
+	if a.Default != "" {
+		constraints += " DEFAULT " + Database.DefaultValue(a.Default)
+	}
+
+	if a.Identity != "" {
+		constraints += Database.Identity(a.Identity)
+	}
+
+	typ := a.Type.ToSQL()
+	if a.IsEnum() {
+		typ = Database.EnumType(a.Enum())
 	}
 
-	return fmt.Sprintf("%s %s%s", a.Name, a.Type.ToSQL(), nullableStr)
+	return fmt.Sprintf("%s %s%s", a.Name, typ, constraints)
 }
 
+// Creatable returns whether the column is given by the create query and
+// message, defaulted and BY DEFAULT identity columns only when asked for,
+// generated and ALWAYS identity ones never
+func (a Attribute) Creatable() bool {
+	if a.Generated != "" || a.Identity == IdentityAlways {
+		return false
+	}
+
+	return (a.Default == "" && a.Identity == "") || a.Create
+}
+
+const (
+	IdentityAlways    = "ALWAYS"     // GENERATED ALWAYS AS IDENTITY, rejecting given values
+	IdentityByDefault = "BY DEFAULT" // GENERATED BY DEFAULT AS IDENTITY, taking given values
+)
+
 func (a Attribute) ToProto() string {
-	return fmt.Sprintf("%s %s", a.Type.ToProto(), strings.ReplaceAll(strcase.ToCamel(a.Name), "id", "ID"))
+	typ := a.Type.ToProto()
+	if a.IsEnum() {
+		typ = a.Enum().ProtoName()
+	}
+
+	return fmt.Sprintf("%s %s", typ, a.protoName())
+}
+
+func (a Attribute) protoName() string {
+	return strings.ReplaceAll(strcase.ToCamel(a.Name), "id", "ID")
+}
+
+// GoType returns the go type sqlc generates for the column, its null type
+// when nullable (i.e. sql.NullString)
+func (a Attribute) GoType() string {
+	if null, _ := a.goNull(); null != "" {
+		return null
+	}
+
+	if a.goEnum() {
+		return a.Enum().GoName()
+	}
+
+	return a.goAttributeType().ToGo()
+}
+
+// TestValue returns a go expression of a value of the column for generated
+// tests, the first value of enums
+func (a Attribute) TestValue() string {
+	if a.goEnum() {
+		return a.Enum().GoValue(a.Values[0])
+	}
+
+	if a.IsEnum() {
+		return strconv.Quote(a.Values[0])
+	}
+
+	return a.goAttributeType().TestValue()
 }
 
 type Index struct {
-	Name    string
-	Type    string // (i.e. BTREE)
-	Columns []string
+	Name    string   `yaml:"name"`
+	Type    string   `yaml:"type"` // (i.e. BTREE)
+	Columns []string `yaml:"columns"`
 }
 
 type Indexes []Index
@@ -82,10 +177,13 @@ func (i Indexes) Any(f func(i Index) bool) bool {
 type Attributes []Attribute
 
 type CreateTable struct {
-	TableName  string
-	Attributes Attributes
-	Indexes    Indexes
-	Owner      string
+	TableName         string             `yaml:"table_name"`
+	Attributes        Attributes         `yaml:"attributes"`
+	Indexes           Indexes            `yaml:"indexes,omitempty"`
+	PrimaryKey        []string           `yaml:"primary_key,omitempty"` // columns of the PRIMARY KEY, id is the key when not declared
+	UniqueConstraints []UniqueConstraint `yaml:"unique_constraints,omitempty"`
+	CheckConstraints  []CheckConstraint  `yaml:"check_constraints,omitempty"`
+	Owner             string             `yaml:"owner,omitempty"`
 }
 
 func (a Attributes) Any(f func(a Attribute) bool) bool {
@@ -98,6 +196,13 @@ func (a Attributes) Any(f func(a Attribute) bool) bool {
 	return false
 }
 
+// Has returns whether an attribute is named name
+func (a Attributes) Has(name string) bool {
+	return a.Any(func(attr Attribute) bool {
+		return attr.Name == name
+	})
+}
+
 func (a Attributes) Select(f func(a Attribute) bool) []Attribute {
 	attrs := make([]Attribute, 0)
 	for _, attribute := range a {
@@ -111,6 +216,9 @@ func (a Attributes) Select(f func(a Attribute) bool) []Attribute {
 
 type CrudOption string
 
+// CrudOptions are the crud options goils generates messages and queries for
+var CrudOptions = []CrudOption{"show", "index", "create"}
+
 func (c CrudOption) MessageName() string {
 	switch c {
 	case "show", "index", "create":
@@ -121,9 +229,14 @@ func (c CrudOption) MessageName() string {
 }
 
 type Resource struct {
-	CreateTable
-	CrudOptions []CrudOption
-	Package     string
+	CreateTable    `yaml:",inline"`
+	CrudOptions    []CrudOption     `yaml:"crud_options,omitempty"`
+	Package        string           `yaml:"package,omitempty"`
+	ProtoNullable  string           `yaml:"proto_nullable,omitempty"` // optional (default) or wrapper
+	ProtoValidate  bool             `yaml:"proto_validate,omitempty"` // adds the protoc-gen-validate rules of the column limits
+	HasManyThrough []HasManyThrough `yaml:"has_many_through,omitempty"`
+
+	join *join // set on the join resources of HasManyThrough
 }
 
 type ProtoMessage struct {
@@ -131,6 +244,10 @@ type ProtoMessage struct {
 	ModelName  string
 	Type       string
 	Attributes []Attribute
+	Key        []Attribute  // the primary key columns show, update and delete find the row by
+	Scope      []Attribute  // the foreign key columns an index lists the rows of a parent by
+	Related    *CreateTable // the table related rows are listed from, through the resource
+	On         string       // the join condition of Related (i.e. sms_tag.tag_id = tag.id)
 	Verb       ExtString
 	Noun       ExtString
 }
@@ -167,7 +284,28 @@ func (pm ProtoMessage) ResponseMessageName() ExtString {
 // CrudFuncName returns the name of the crud function
 // TODO: ProtoMessage?? should we call it something else
 func (pm ProtoMessage) CrudFuncName() string {
-	return crudPrefix(pm.Type) + strcase.ToCamel(pm.ModelName)
+	prefix := crudPrefix(pm.Type)
+	if prefix == "" || len(pm.Scope) > 0 {
+		return pm.Name // named after the relation (i.e. ListSettingsByLocation)
+	}
+
+	return prefix + strcase.ToCamel(pm.ModelName)
+}
+
+// Exec returns whether the query returns no rows (i.e. attach, or create when
+// the Database cannot return the created row)
+func (pm ProtoMessage) Exec() bool {
+	return pm.Type == "attach" || pm.Type == "detach" || (pm.Type == "create" && !Database.Returning())
+}
+
+// Columns returns the columns of the rows the query returns, the ones of the
+// Related table or of the resource
+func (pm ProtoMessage) Columns(resource Resource) Attributes {
+	if pm.Related != nil {
+		return pm.Related.Attributes
+	}
+
+	return resource.Attributes
 }
 
 func crudPrefix(typ string) string {
@@ -188,33 +326,76 @@ func crudPrefix(typ string) string {
 }
 
 // CrudAttributes returns the attributes to send for crud action
-// TODO: we might need to reference table here so we know the index attributes
 func (pm ProtoMessage) CrudAttributes() []string {
+	args := pm.Args()
+	names := make([]string, len(args))
+	for i, attr := range args {
+		names[i] = attr.Name
+	}
+
+	return names
+}
+
+// Args returns the columns the crud action finds its rows by, the Key of
+// show, update and delete and the Scope of index
+func (pm ProtoMessage) Args() []Attribute {
 	switch pm.Type {
-	case "show":
-		return []string{"id"}
-	case "index":
-		return []string{}
+	case "show", "delete", "update", "attach", "detach": // TODO: update needs to include pertinent attributes too
+		return pm.Key
+	case "index", "related":
+		return pm.Scope
 	case "create":
-		return []string{} // TODO: needs to be all pertinent attributes
-	case "delete":
-		return []string{"id"}
-	case "update":
-		return []string{"id"} // TODO: needs to include pertinent attributes too
+		return []Attribute{} // TODO: needs to be all pertinent attributes
 	}
 
-	return []string{}
+	return []Attribute{}
 }
 
+// TestCrudAttributes returns the arguments generated tests call the crud
+// function with, sqlc takes a params struct for several
+// (i.e. GetSettingParams{LocationID: tt.args.locationId, Name: tt.args.name})
 func (pm ProtoMessage) TestCrudAttributes() []string {
-	attrs := pm.CrudAttributes()
-	results := make([]string, len(attrs))
+	return pm.callArgs(func(attr Attribute) string {
+		return "tt.args." + attr.ArgName()
+	})
+}
+
+// callArgs returns the arguments of the crud function, value giving the one of
+// each Args column
+func (pm ProtoMessage) callArgs(value func(attr Attribute) string) []string {
+	args := pm.Args()
+	if len(args) < 2 {
+		results := make([]string, len(args))
+		for i, attr := range args {
+			results[i] = value(attr)
+		}
+
+		return results
+	}
 
-	for i, r := range attrs {
-		results[i] = "tt.args." + r
+	fields := make([]string, len(args))
+	for i, attr := range args {
+		fields[i] = fmt.Sprintf("%s: %s", attr.GoName(), value(attr))
 	}
 
-	return results
+	return []string{fmt.Sprintf("%sParams{%s}", pm.CrudFuncName(), strings.Join(fields, ", "))}
+}
+
+// ArgsDescription returns the Args columns for humans (i.e. "locationid and name")
+func (pm ProtoMessage) ArgsDescription() string {
+	return strings.Join(pm.CrudAttributes(), " and ")
+}
+
+// Condition returns the WHERE condition of the Args columns, numbered from $1
+// (i.e. locationid = $1 AND name = $2, or locationid = ? AND name = ? in mysql)
+func (pm ProtoMessage) Condition() string {
+	args := pm.Args()
+	conditions := make([]string, len(args))
+	for i, attr := range args {
+		conditions[i] = fmt.Sprintf("%s = %s", attr.Name, Database.Placeholder(i+1))
+	}
+
+	return strings.Join(conditions, " AND ")
 }
 
 func newProtoMessage(resource Resource, typ string) ProtoMessage {
@@ -224,18 +405,20 @@ func newProtoMessage(resource Resource, typ string) ProtoMessage {
 
 	switch typ {
 	case "show":
-		return newShowProtoMessage(resource)
+		pm = newShowProtoMessage(resource)
 	case "index":
-		return newIndexProtoMessage(resource)
+		pm = newIndexProtoMessage(resource)
 	case "create":
-		return newCreateProtoMessage(resource)
+		pm = newCreateProtoMessage(resource)
 	}
+	pm.Key = resource.KeyAttributes()
+
 	return pm
 }
 
 func newIndexProtoMessage(resource Resource) ProtoMessage {
 	suitable := resource.Attributes.Select(func(attr Attribute) bool {
-		return attr.Name != "id"
+		return !resource.IsKey(attr.Name)
 	})
 
 	return ProtoMessage{
@@ -246,9 +429,11 @@ func newIndexProtoMessage(resource Resource) ProtoMessage {
 	}
 }
 
+// newShowProtoMessage requests a row by its key, followed by the other indexed
+// attributes
 func newShowProtoMessage(resource Resource) ProtoMessage {
 	indexed := resource.Attributes.Select(func(attr Attribute) bool {
-		return resource.Indexes.Any(func(index Index) bool {
+		return !resource.IsKey(attr.Name) && resource.Indexes.Any(func(index Index) bool {
 			return index.HasAttribute(attr.Name)
 		})
 	})
@@ -257,14 +442,15 @@ func newShowProtoMessage(resource Resource) ProtoMessage {
 		Type:       "show",
 		Name:       strcase.ToCamel(resource.TableName),
 		ModelName:  strcase.ToCamel(resource.TableName),
-		Attributes: indexed,
+		Attributes: append(resource.KeyAttributes(), indexed...),
 	}
 }
 
 func newCreateProtoMessage(resource Resource) ProtoMessage {
-	// might need to handle indexes differently
+	// might need to handle indexes differently, the implicit id is left to
+	// the database while declared key columns are given unless defaulted
 	suitable := resource.Attributes.Select(func(attr Attribute) bool {
-		return attr.Name != "id"
+		return !(len(resource.PrimaryKey) == 0 && attr.Name == "id") && attr.Creatable()
 	})
 
 	return ProtoMessage{
@@ -275,11 +461,155 @@ func newCreateProtoMessage(resource Resource) ProtoMessage {
 	}
 }
 
+// CrudMessages returns the messages of the crud options, index is followed
+// by the ones listing the rows of each parent. Join resources have the
+// messages of their relation instead
 func (r Resource) CrudMessages() []ProtoMessage {
-	messages := make([]ProtoMessage, len(r.CrudOptions))
-	for i, msg := range r.CrudOptions {
-		messages[i] = newProtoMessage(r, msg.MessageName())
+	if r.join != nil {
+		return r.join.messages(r)
+	}
+
+	messages := make([]ProtoMessage, 0, len(r.CrudOptions))
+	for _, msg := range r.CrudOptions {
+		messages = append(messages, newProtoMessage(r, msg.MessageName()))
+		if msg != "index" {
+			continue
+		}
+
+		for _, attr := range r.Attributes {
+			if attr.References != nil {
+				messages = append(messages, newIndexByProtoMessage(r, attr))
+			}
+		}
 	}
 
 	return messages
 }
+
+// GoType returns the go type of the attribute named name
+func (r Resource) GoType(name string) string {
+	for _, attr := range r.Attributes {
+		if attr.Name == name {
+			return attr.GoType()
+		}
+	}
+
+	return ""
+}
+
+// ProtoImports returns the files defining the proto types of the crud messages
+func (r Resource) ProtoImports() []string {
+	imports := make([]string, 0)
+	for _, message := range r.CrudMessages() {
+		for _, attr := range message.Attributes {
+			if r.protoWrapper(attr) != "" {
+				imports = appendUnique(imports, wrappersImport)
+				continue
+			}
+
+			info, _ := LookupType(attr.Type)
+			imports = appendUnique(imports, info.ProtoImport)
+		}
+
+		for _, attr := range message.Attributes {
+			if r.ProtoRules(attr) != "" {
+				imports = appendUnique(imports, validateImport)
+			}
+		}
+	}
+	sort.Strings(imports)
+
+	return imports
+}
+
+// TestStdImports returns the standard library packages of the generated tests,
+// the attribute go types included
+func (r Resource) TestStdImports() []string {
+	imports := append(r.goImports(true), "context", "testing")
+	if path := r.errorImport(); isStdImport(path) {
+		imports = appendUnique(imports, path)
+	}
+	sort.Strings(imports)
+
+	return imports
+}
+
+// TestImports returns the other packages of the attribute go types, and the
+// database driver when the tests cover unique violations
+func (r Resource) TestImports() []string {
+	imports := r.goImports(false)
+	if path := r.errorImport(); path != "" && !isStdImport(path) {
+		imports = appendUnique(imports, path)
+		sort.Strings(imports)
+	}
+
+	return imports
+}
+
+// errorImport returns the package of the errors the tests of unique violations
+// return, none when there are no such tests
+func (r Resource) errorImport() string {
+	if len(r.UniqueConstraints) == 0 || !r.hasCrudOption("create") {
+		return ""
+	}
+
+	return Database.ErrorImport()
+}
+
+func (r Resource) hasCrudOption(option CrudOption) bool {
+	for _, o := range r.CrudOptions {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Resource) goImports(std bool) []string {
+	attributes := r.Attributes
+	for _, message := range r.CrudMessages() {
+		if message.Related != nil {
+			attributes = append(append(Attributes{}, attributes...), message.Related.Attributes...)
+		}
+	}
+
+	imports := make([]string, 0)
+	for _, attr := range attributes {
+		info := attr.goAttributeType().goInfo()
+		if null, _ := attr.goNull(); null == "" {
+			info.GoNullImport = ""
+		}
+
+		for _, path := range []string{info.GoImport, info.GoNullImport} {
+			if path == "" || std != isStdImport(path) {
+				continue
+			}
+
+			imports = appendUnique(imports, path)
+		}
+	}
+	sort.Strings(imports)
+
+	return imports
+}
+
+// isStdImport returns whether path is a standard library package (i.e. not
+// github.com/lib/pq)
+func isStdImport(path string) bool {
+	return path != "" && !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
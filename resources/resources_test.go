@@ -71,6 +71,13 @@ func Test_newProtoMessage(t *testing.T) {
 						Nullable: false,
 					},
 				},
+				Key: []Attribute{
+					{
+						Name:     "id",
+						Type:     "UUID",
+						Nullable: false,
+					},
+				},
 			},
 		},
 		{
@@ -98,6 +105,56 @@ func Test_newProtoMessage(t *testing.T) {
 						Nullable: false,
 					},
 				},
+				Key: []Attribute{
+					{
+						Name:     "id",
+						Type:     "UUID",
+						Nullable: false,
+					},
+				},
+			},
+		},
+		{
+			name: "given 'show' and a composite primary key requests by the key",
+			args: args{
+				resource: Resource{
+					CreateTable: CreateTable{
+						TableName:  "setting",
+						Attributes: table.Attributes,
+						PrimaryKey: []string{"locationid", "name"},
+					},
+					CrudOptions: []CrudOption{"show"},
+				},
+				typ: "show",
+			},
+			want: ProtoMessage{
+				Name:      "Setting",
+				ModelName: "Setting",
+				Type:      "show",
+				Attributes: []Attribute{
+					{
+						Name:     "locationid",
+						Type:     "UUID",
+						Nullable: false,
+					},
+					{
+						Name:     "name",
+						Type:     "string",
+						Nullable: false,
+					},
+				},
+				Key: []Attribute{
+					{
+						Name:     "locationid",
+						Type:     "UUID",
+						Nullable: false,
+					},
+					{
+						Name:     "name",
+						Type:     "string",
+						Nullable: false,
+					},
+				},
 			},
 		},
 	}
@@ -186,6 +243,7 @@ func TestProtoMessage_CrudAttributes(t *testing.T) {
 		ModelName  string
 		Type       string
 		Attributes []Attribute
+		Key        []Attribute
 	}
 	tests := []struct {
 		name   string
@@ -196,6 +254,7 @@ func TestProtoMessage_CrudAttributes(t *testing.T) {
 			name: "given Type is 'show' returns id",
 			fields: fields{
 				Type: "show",
+				Key:  []Attribute{{Name: "id", Type: "UUID"}},
 			},
 			want: []string{
 				"id",
@@ -219,15 +278,28 @@ func TestProtoMessage_CrudAttributes(t *testing.T) {
 			name: "given Type is 'update' returns id",
 			fields: fields{
 				Type: "update",
+				Key:  []Attribute{{Name: "id", Type: "UUID"}},
 			},
 			want: []string{
 				"id",
 			},
 		},
+		{
+			name: "given Type is 'show' and a composite key returns the key",
+			fields: fields{
+				Type: "show",
+				Key:  []Attribute{{Name: "locationid", Type: "UUID"}, {Name: "name", Type: "string"}},
+			},
+			want: []string{
+				"locationid",
+				"name",
+			},
+		},
 		{
 			name: "given Type is 'delete' returns id",
 			fields: fields{
 				Type: "delete",
+				Key:  []Attribute{{Name: "id", Type: "UUID"}},
 			},
 			want: []string{
 				"id",
@@ -242,6 +314,7 @@ func TestProtoMessage_CrudAttributes(t *testing.T) {
 					ModelName:  tt.fields.ModelName,
 					Type:       tt.fields.Type,
 					Attributes: tt.fields.Attributes,
+					Key:        tt.fields.Key,
 				}
 				got := pm.CrudAttributes()
 				So(got, ShouldResemble, tt.want)
@@ -0,0 +1,50 @@
+package resources
+
+import "sort"
+
+// SeedAttributes returns the columns the SQLite tests insert a row with, the
+// ones the database does not generate
+func (r Resource) SeedAttributes() Attributes {
+	return r.Attributes.Select(func(attr Attribute) bool {
+		return attr.Generated == ""
+	})
+}
+
+// SQLiteMessages returns the crud messages whose queries the SQLite tests run
+// against the inserted row (i.e. show and index)
+func (r Resource) SQLiteMessages() []ProtoMessage {
+	messages := make([]ProtoMessage, 0)
+	for _, message := range r.CrudMessages() {
+		if message.Type == "show" || message.Type == "index" {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// SQLiteTestStdImports returns the standard library packages of the SQLite
+// tests, the attribute go types included
+func (r Resource) SQLiteTestStdImports() []string {
+	imports := r.goImports(true)
+	for _, path := range []string{"database/sql", "io/ioutil", "testing"} {
+		imports = appendUnique(imports, path)
+	}
+	if len(r.SQLiteMessages()) > 0 {
+		imports = appendUnique(imports, "context")
+	}
+	sort.Strings(imports)
+
+	return imports
+}
+
+// SQLiteTestImports returns the other packages of the attribute go types
+func (r Resource) SQLiteTestImports() []string {
+	return r.goImports(false)
+}
+
+// SeededCrudAttributes returns the arguments the SQLite tests call the crud
+// function with, the values of the inserted row
+func (pm ProtoMessage) SeededCrudAttributes() []string {
+	return pm.callArgs(Attribute.TestWant)
+}
@@ -0,0 +1,186 @@
+package resources
+
+import (
+	"fmt"
+
+	"github.com/iancoleman/strcase"
+
+	. "weavelab.xyz/goils/extstring"
+)
+
+// HasManyThrough relates the resource to the rows of another resource through
+// a join table holding both keys (i.e. sms has many tag through sms_tag)
+type HasManyThrough struct {
+	Table   string `yaml:"table"`             // the related resource
+	Through string `yaml:"through,omitempty"` // the join table, <table>_<related table> when not set
+}
+
+// join is what a join resource relates, the columns referencing the owner and
+// the related resource and the related table the list query returns
+type join struct {
+	owner   Attribute
+	related Attribute
+	table   CreateTable
+}
+
+// JoinTable returns the name of the join table of resource
+func (h HasManyThrough) JoinTable(resource Resource) string {
+	if h.Through != "" {
+		return h.Through
+	}
+
+	return resource.TableName + "_" + h.Table
+}
+
+// JoinResources returns the join resources of every has_many_through of the
+// definition
+func (d Definition) JoinResources() ([]Resource, error) {
+	joins := make([]Resource, 0)
+	for _, resource := range d.Resources {
+		for i := range resource.HasManyThrough {
+			join, err := d.joinResource(resource, i)
+			if err != nil {
+				return nil, err
+			}
+			joins = append(joins, join)
+		}
+	}
+
+	return joins, nil
+}
+
+// FindJoin returns the join resource whose join table is name
+func (d Definition) FindJoin(name string) (Resource, bool, error) {
+	for _, resource := range d.Resources {
+		for i, through := range resource.HasManyThrough {
+			if through.JoinTable(resource) == name {
+				join, err := d.joinResource(resource, i)
+
+				return join, err == nil, err
+			}
+		}
+	}
+
+	return Resource{}, false, nil
+}
+
+// joinResource builds the join table of the i-th has_many_through of owner: a
+// column referencing each resource's key (i.e. sms_id and tag_id), deleted with
+// either row, both making up its primary key
+func (d Definition) joinResource(owner Resource, i int) (Resource, error) {
+	through := owner.HasManyThrough[i]
+	field := fmt.Sprintf("%s: has_many_through[%d]", owner.TableName, i)
+
+	related, ok := d.Find(through.Table)
+	if !ok {
+		return Resource{}, fmt.Errorf("%s: %q is not defined", field, through.Table)
+	}
+
+	ownerAttr, err := joinAttribute(field, owner)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	relatedAttr, err := joinAttribute(field, related)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	return Resource{
+		CreateTable: CreateTable{
+			TableName:  through.JoinTable(owner),
+			Attributes: Attributes{ownerAttr, relatedAttr},
+			PrimaryKey: []string{ownerAttr.Name, relatedAttr.Name},
+			Owner:      owner.Owner,
+		},
+		Package: owner.Package,
+		join: &join{
+			owner:   ownerAttr,
+			related: relatedAttr,
+			table:   related.CreateTable,
+		},
+	}, nil
+}
+
+// validateHasManyThrough reports has_many_through without a table
+func (r Resource) validateHasManyThrough(d *Diagnostics) {
+	for i, through := range r.HasManyThrough {
+		field := fmt.Sprintf("has_many_through[%d]", i)
+
+		switch {
+		case through.Table == "":
+			d.add(field+".table", "is required")
+		case through.Table == r.TableName:
+			d.add(field+".table", "a resource cannot have many of itself")
+		}
+	}
+}
+
+// joinAttribute returns the join table column referencing the key of resource,
+// which has to be its primary key or unique for the foreign key to hold
+func joinAttribute(field string, resource Resource) (Attribute, error) {
+	key := resource.KeyAttributes()
+	if len(key) != 1 {
+		return Attribute{}, fmt.Errorf("%s: %q needs a primary key of one column", field, resource.TableName)
+	}
+	if !resource.IsUnique([]string{key[0].Name}) {
+		return Attribute{}, fmt.Errorf("%s: %s.%s is neither the primary key nor a unique constraint of %s", field, resource.TableName, key[0].Name, resource.TableName)
+	}
+
+	return Attribute{
+		Name: resource.TableName + "_" + key[0].Name,
+		Type: key[0].Type,
+		References: &Reference{
+			Table:    resource.TableName,
+			Column:   key[0].Name,
+			OnDelete: "CASCADE",
+		},
+	}, nil
+}
+
+// RelatedTables returns the tables the queries of the resource select from
+// besides its own (i.e. tag for the sms_tag join table), sqlc needs their schema
+func (r Resource) RelatedTables() []CreateTable {
+	if r.join == nil {
+		return nil
+	}
+
+	return []CreateTable{r.join.table}
+}
+
+// messages returns the messages attaching, detaching and listing the related
+// rows of the join resource (i.e. AttachTagToSms, DetachTagFromSms and
+// ListTagsBySms)
+func (j join) messages(resource Resource) []ProtoMessage {
+	owner := strcase.ToCamel(j.owner.References.Table)
+	related := strcase.ToCamel(j.related.References.Table)
+	key := resource.KeyAttributes()
+
+	return []ProtoMessage{
+		{
+			Type:       "attach",
+			Name:       fmt.Sprintf("Attach%sTo%s", related, owner),
+			ModelName:  strcase.ToCamel(resource.TableName),
+			Attributes: key,
+			Key:        key,
+		},
+		{
+			Type:       "detach",
+			Name:       fmt.Sprintf("Detach%sFrom%s", related, owner),
+			ModelName:  strcase.ToCamel(resource.TableName),
+			Attributes: key,
+			Key:        key,
+		},
+		{
+			Type:       "related",
+			Name:       fmt.Sprintf("List%sBy%s", ExtString(related).Pluralize(), owner),
+			ModelName:  related,
+			Attributes: []Attribute{j.owner},
+			Key:        key,
+			Scope:      []Attribute{j.owner},
+			Related:    &j.table,
+			On: fmt.Sprintf("%s.%s = %s.%s", resource.TableName, j.related.Name,
+				j.table.TableName, j.related.References.ReferencedColumn()),
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefinition_joinResources(t *testing.T) {
+	definition := Definition{Resources: []Resource{
+		{
+			Package:        "main",
+			CreateTable:    CreateTable{TableName: "sms", Attributes: Attributes{{Name: "id", Type: "UUID"}, {Name: "text", Type: "text"}}},
+			HasManyThrough: []HasManyThrough{{Table: "tag"}},
+		},
+		{
+			Package:     "main",
+			CreateTable: CreateTable{TableName: "tag", Attributes: Attributes{{Name: "id", Type: "UUID"}, {Name: "name", Type: "text"}}},
+		},
+	}}
+
+	join, ok, err := definition.FindJoin("sms_tag")
+	if err != nil || !ok {
+		t.Fatalf("FindJoin() = %v, %v", ok, err)
+	}
+
+	Convey("the join table references both keys, deleted with either row", t, func() {
+		migration := GenerateMigration(join)[0].Output
+		So(migration, ShouldContainSubstring, "\tsms_id UUID NOT NULL,\n\ttag_id UUID NOT NULL,\n"+
+			"\tPRIMARY KEY (sms_id, tag_id),\n"+
+			"\tCONSTRAINT sms_tag_sms_id_fkey FOREIGN KEY (sms_id) REFERENCES sms (id) ON DELETE CASCADE,\n"+
+			"\tCONSTRAINT sms_tag_tag_id_fkey FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE\n)")
+		So(migration, ShouldContainSubstring, "CREATE INDEX IF NOT EXISTS idx_sms_tag_tag_id\n")
+		So(migration, ShouldNotContainSubstring, "idx_sms_tag_sms_id")
+	})
+
+	Convey("the queries attach, detach and list the related rows", t, func() {
+		queries := GenerateSQL(join)[0].Output
+		So(queries, ShouldContainSubstring, "-- name: AttachTagToSms :exec\nINSERT INTO sms_tag (sms_id, tag_id)\nVALUES ($1, $2);")
+		So(queries, ShouldContainSubstring, "-- name: DetachTagFromSms :exec\nDELETE FROM sms_tag\nWHERE sms_id = $1 AND tag_id = $2;")
+		So(queries, ShouldContainSubstring, "-- name: ListTagsBySms :many\nSELECT tag.* FROM tag\nJOIN sms_tag ON sms_tag.tag_id = tag.id\nWHERE sms_id = $1;")
+	})
+
+	Convey("the sqlc schema holds the related table", t, func() {
+		schema := GenerateSQL(join)[2].Output
+		So(schema, ShouldContainSubstring, "CREATE TABLE tag (\n    id UUID NOT NULL,\n    name text NOT NULL,\n    PRIMARY KEY (id)\n);\nCREATE TABLE sms_tag (")
+	})
+
+	Convey("the messages hold the keys", t, func() {
+		proto := GenerateProto(join)[0].Output
+		So(proto, ShouldContainSubstring, "message AttachTagToSms {\n  shared.UUID SmsId = 1;\n  shared.UUID TagId = 2;\n}")
+		So(proto, ShouldContainSubstring, "message DetachTagFromSms {\n  shared.UUID SmsId = 1;\n  shared.UUID TagId = 2;\n}")
+		So(proto, ShouldContainSubstring, "message ListTagsBySms {\n  shared.UUID SmsId = 1;\n}")
+	})
+
+	Convey("the tests exec attach and detach and list the related rows", t, func() {
+		tests := GenerateTests(join)[0].Output
+		So(tests, ShouldContainSubstring, "query := mock.ExpectExec(\"^INSERT INTO (.+)\")")
+		So(tests, ShouldContainSubstring, "err := pg.AttachTagToSms(tt.args.ctx, AttachTagToSmsParams{SmsID: tt.args.smsId, TagID: tt.args.tagId})")
+		So(tests, ShouldContainSubstring, "query := mock.ExpectExec(\"^DELETE FROM (.+)\")")
+		So(tests, ShouldContainSubstring, "\tcolumns := []string{\"id\", \"name\"}\n")
+		So(tests, ShouldContainSubstring, "got, err := pg.ListTagsBySms(tt.args.ctx, tt.args.smsId)")
+		So(tests, ShouldContainSubstring, "So(got, ShouldResemble, []Tag{tt.want})")
+	})
+
+	Convey("JoinResources returns the join table of every has_many_through", t, func() {
+		joins, err := definition.JoinResources()
+		So(err, ShouldBeNil)
+		So(len(joins), ShouldEqual, 1)
+		So(joins[0].TableName, ShouldEqual, "sms_tag")
+		So(joins[0].PrimaryKey, ShouldResemble, []string{"sms_id", "tag_id"})
+	})
+
+	Convey("the join table references the declared key of the related resource", t, func() {
+		named := definition
+		named.Resources = append([]Resource{}, definition.Resources...)
+		named.Resources[1].PrimaryKey = []string{"name"}
+
+		joins, err := named.JoinResources()
+		So(err, ShouldBeNil)
+		So(joins[0].Attributes[1], ShouldResemble, Attribute{
+			Name: "tag_name", Type: "text", References: &Reference{Table: "tag", Column: "name", OnDelete: "CASCADE"},
+		})
+		So(joins[0].ValidateReferenced(named.Resources), ShouldBeEmpty)
+		So(GenerateMigration(named.Resources[1])[0].Output, ShouldContainSubstring, "\tPRIMARY KEY (name)\n")
+		So(GenerateMigration(joins[0])[0].Output, ShouldContainSubstring, "FOREIGN KEY (tag_name) REFERENCES tag (name) ON DELETE CASCADE")
+	})
+
+	Convey("through names the join table", t, func() {
+		named := definition
+		named.Resources = append([]Resource{}, definition.Resources...)
+		named.Resources[0].HasManyThrough = []HasManyThrough{{Table: "tag", Through: "sms_labels"}}
+
+		_, ok, err := named.FindJoin("sms_labels")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("related resources must be defined with a single column key", t, func() {
+		invalid := definition
+		invalid.Resources = []Resource{definition.Resources[0]}
+		_, err := invalid.JoinResources()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `sms: has_many_through[0]: "tag" is not defined`)
+
+		invalid.Resources = append(invalid.Resources, Resource{CreateTable: CreateTable{TableName: "tag", Attributes: Attributes{{Name: "name", Type: "text"}}}})
+		_, err = invalid.JoinResources()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `sms: has_many_through[0]: "tag" needs a primary key of one column`)
+	})
+
+	Convey("Validate reports has_many_through without a table", t, func() {
+		invalid := definition.Resources[0]
+		invalid.HasManyThrough = []HasManyThrough{{}, {Table: "sms"}}
+		So(invalid.Validate(), ShouldResemble, Diagnostics{
+			{Field: "has_many_through[0].table", Message: "is required"},
+			{Field: "has_many_through[1].table", Message: "a resource cannot have many of itself"},
+		})
+	})
+}
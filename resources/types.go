@@ -0,0 +1,334 @@
+package resources
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TypeInfo is how an AttributeType is generated in each output
+type TypeInfo struct {
+	SQL          string `yaml:"sql"`                      // column type (i.e. varchar(120))
+	MySQL        string `yaml:"mysql,omitempty"`          // column type in mysql, SQL when not set (i.e. DATETIME)
+	SQLite       string `yaml:"sqlite,omitempty"`         // column type in sqlite, SQL when not set (i.e. TEXT)
+	Proto        string `yaml:"proto"`                    // field type (i.e. google.protobuf.Timestamp)
+	ProtoImport  string `yaml:"proto_import,omitempty"`   // file defining Proto (i.e. google/protobuf/timestamp.proto)
+	ProtoWrapper string `yaml:"proto_wrapper,omitempty"`  // wrapper of nullable columns (i.e. google.protobuf.StringValue)
+	Go           string `yaml:"go"`                       // type sqlc generates (i.e. time.Time)
+	GoImport     string `yaml:"go_import,omitempty"`      // package of Go (i.e. time)
+	GoNull       string `yaml:"go_null,omitempty"`        // type sqlc generates for nullable columns (i.e. sql.NullTime)
+	GoNullImport string `yaml:"go_null_import,omitempty"` // package of GoNull (i.e. database/sql)
+	TestValue    string `yaml:"test_value,omitempty"`     // go expression of a Go value for tests (i.e. time.Now())
+}
+
+const (
+	uuidImport      = "weavelab.xyz/monorail/shared/wlib/uuid"
+	timestampImport = "google/protobuf/timestamp.proto"
+	wrappersImport  = "google/protobuf/wrappers.proto"
+	validateImport  = "validate/validate.proto"
+	sqlImport       = "database/sql"
+	pqtypeImport    = "github.com/sqlc-dev/pqtype"
+)
+
+// AttributeTypes are the types goils generates columns for
+var AttributeTypes = []AttributeType{
+	"UUID",
+	"string",
+	"text",
+	"boolean",
+	"smallint",
+	"integer",
+	"bigint",
+	"real",
+	"double precision",
+	"numeric",
+	"varchar",
+	"date",
+	"timestamp",
+	"timestamptz",
+	"interval",
+	"jsonb",
+	"bytea",
+	"inet",
+}
+
+// builtinTypes are the AttributeTypes goils ships with, before any is registered
+var builtinTypes = append([]AttributeType{}, AttributeTypes...)
+
+// builtinRegistry is the typeRegistry goils ships with, restored by ResetTypes
+var builtinRegistry = copyTypes(typeRegistry)
+
+// typeRegistry maps every AttributeType to how it is generated, AttributeTypes
+// lists them in order
+var typeRegistry = map[AttributeType]TypeInfo{
+	"UUID": {
+		SQL: "UUID", MySQL: "CHAR(36)", SQLite: "TEXT", Proto: "shared.UUID",
+		Go: "uuid.UUID", GoImport: uuidImport, GoNull: "uuid.NullUUID", GoNullImport: uuidImport,
+		TestValue: "uuid.NewV4()",
+	},
+	"string": {
+		SQL: "varchar(120)", SQLite: "TEXT", Proto: "string", ProtoWrapper: "google.protobuf.StringValue",
+		Go: "string", GoNull: "sql.NullString", GoNullImport: sqlImport,
+		TestValue: `"string"`,
+	},
+	"text": {
+		SQL: "text", MySQL: "TEXT", SQLite: "TEXT", Proto: "string", ProtoWrapper: "google.protobuf.StringValue",
+		Go: "string", GoNull: "sql.NullString", GoNullImport: sqlImport,
+		TestValue: `"text"`,
+	},
+	"boolean": {
+		SQL: "boolean", SQLite: "BOOLEAN", Proto: "bool", ProtoWrapper: "google.protobuf.BoolValue",
+		Go: "bool", GoNull: "sql.NullBool", GoNullImport: sqlImport,
+		TestValue: "true",
+	},
+	"smallint": {
+		SQL: "smallint", SQLite: "INTEGER", Proto: "int32", ProtoWrapper: "google.protobuf.Int32Value",
+		Go: "int16", GoNull: "sql.NullInt16", GoNullImport: sqlImport,
+		TestValue: "int16(1)",
+	},
+	"integer": {
+		SQL: "integer", SQLite: "INTEGER", Proto: "int32", ProtoWrapper: "google.protobuf.Int32Value",
+		Go: "int32", GoNull: "sql.NullInt32", GoNullImport: sqlImport,
+		TestValue: "int32(1)",
+	},
+	"bigint": {
+		SQL: "bigint", SQLite: "INTEGER", Proto: "int64", ProtoWrapper: "google.protobuf.Int64Value",
+		Go: "int64", GoNull: "sql.NullInt64", GoNullImport: sqlImport,
+		TestValue: "int64(1)",
+	},
+	"real": {
+		SQL: "real", MySQL: "FLOAT", SQLite: "REAL", Proto: "float", ProtoWrapper: "google.protobuf.FloatValue",
+		Go: "float32", GoNull: "sql.NullFloat64", GoNullImport: sqlImport,
+		TestValue: "float32(1.5)",
+	},
+	"double precision": {
+		SQL: "double precision", MySQL: "DOUBLE", SQLite: "REAL", Proto: "double", ProtoWrapper: "google.protobuf.DoubleValue",
+		Go: "float64", GoNull: "sql.NullFloat64", GoNullImport: sqlImport,
+		TestValue: "float64(1.5)",
+	},
+	"numeric": {
+		SQL: "numeric", SQLite: "TEXT", Proto: "string", ProtoWrapper: "google.protobuf.StringValue",
+		Go: "string", GoNull: "sql.NullString", GoNullImport: sqlImport,
+		TestValue: `"1.50"`,
+	},
+	"varchar": {
+		SQL: "varchar", MySQL: "VARCHAR(255)", SQLite: "TEXT", Proto: "string", ProtoWrapper: "google.protobuf.StringValue",
+		Go: "string", GoNull: "sql.NullString", GoNullImport: sqlImport,
+		TestValue: `"varchar"`,
+	},
+	"date": {
+		SQL: "date", SQLite: "DATE", Proto: "google.protobuf.Timestamp", ProtoImport: timestampImport,
+		Go: "time.Time", GoImport: "time", GoNull: "sql.NullTime", GoNullImport: sqlImport,
+		TestValue: "time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC)",
+	},
+	"timestamp": {
+		SQL: "timestamp", MySQL: "DATETIME", SQLite: "DATETIME", Proto: "google.protobuf.Timestamp", ProtoImport: timestampImport,
+		Go: "time.Time", GoImport: "time", GoNull: "sql.NullTime", GoNullImport: sqlImport,
+		TestValue: "time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)",
+	},
+	"timestamptz": {
+		SQL: "timestamptz", MySQL: "DATETIME", SQLite: "DATETIME", Proto: "google.protobuf.Timestamp", ProtoImport: timestampImport,
+		Go: "time.Time", GoImport: "time", GoNull: "sql.NullTime", GoNullImport: sqlImport,
+		TestValue: "time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)",
+	},
+	// sqlc scans intervals into int64 with lib/pq, pgtype.Interval being pgx's
+	"interval": {
+		SQL: "interval", MySQL: "BIGINT", SQLite: "INTEGER", Proto: "google.protobuf.Duration", ProtoImport: "google/protobuf/duration.proto",
+		Go: "int64", GoNull: "sql.NullInt64", GoNullImport: sqlImport,
+		TestValue: "int64(3600000000)",
+	},
+	"jsonb": {
+		SQL: "jsonb", MySQL: "JSON", SQLite: "TEXT", Proto: "google.protobuf.Struct", ProtoImport: "google/protobuf/struct.proto",
+		Go: "json.RawMessage", GoImport: "encoding/json",
+		TestValue: "json.RawMessage(`{}`)",
+	},
+	"bytea": {
+		SQL: "bytea", MySQL: "BLOB", SQLite: "BLOB", Proto: "bytes", ProtoWrapper: "google.protobuf.BytesValue",
+		Go:        "[]byte",
+		TestValue: `[]byte("bytes")`,
+	},
+	"inet": {
+		SQL: "inet", MySQL: "VARCHAR(45)", SQLite: "TEXT", Proto: "string", ProtoWrapper: "google.protobuf.StringValue",
+		Go: "pqtype.Inet", GoImport: pqtypeImport, // nullable too, Valid telling NULL apart
+		TestValue: "pqtype.Inet{Valid: true}",
+	},
+}
+
+// parameterizedTypes are the types taking a length or precision (i.e. numeric(12,2))
+var parameterizedTypes = map[AttributeType]*regexp.Regexp{
+	"string":      regexp.MustCompile(`^\d+$`),
+	"varchar":     regexp.MustCompile(`^\d+$`),
+	"numeric":     regexp.MustCompile(`^\d+(,-?\d+)?$`),
+	"timestamp":   regexp.MustCompile(`^[0-6]$`),
+	"timestamptz": regexp.MustCompile(`^[0-6]$`),
+}
+
+// typeAliases maps the lowercase spelling of SQL types to their AttributeType
+var typeAliases = map[string]AttributeType{
+	"uuid":                        "UUID",
+	"int":                         "integer",
+	"int2":                        "smallint",
+	"int4":                        "integer",
+	"int8":                        "bigint",
+	"float4":                      "real",
+	"float8":                      "double precision",
+	"double":                      "double precision",
+	"bool":                        "boolean",
+	"decimal":                     "numeric",
+	"character varying":           "varchar",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+}
+
+// RegisterType adds (or replaces) the type name, i.e. from the types of a definition file
+func RegisterType(name AttributeType, info TypeInfo) error {
+	switch {
+	case name == "" || strings.HasSuffix(string(name), "[]"):
+		return fmt.Errorf("type %q: invalid name", name)
+	case info.SQL == "":
+		return fmt.Errorf("type %q: sql is required", name)
+	case info.Proto == "":
+		return fmt.Errorf("type %q: proto is required", name)
+	case info.Go == "":
+		return fmt.Errorf("type %q: go is required", name)
+	}
+
+	if _, ok := typeRegistry[name]; !ok {
+		AttributeTypes = append(AttributeTypes, name)
+	}
+	typeRegistry[name] = info
+
+	return nil
+}
+
+// ResetTypes drops the registered types and the defaults applied to the builtin
+// ones, so that the types of a definition do not leak into the next
+func ResetTypes() {
+	typeRegistry = copyTypes(builtinRegistry)
+	AttributeTypes = append([]AttributeType{}, builtinTypes...)
+}
+
+func copyTypes(types map[AttributeType]TypeInfo) map[AttributeType]TypeInfo {
+	copied := make(map[AttributeType]TypeInfo, len(types))
+	for name, info := range types {
+		copied[name] = info
+	}
+
+	return copied
+}
+
+// RegisterTypes registers every type of types, in name order
+func RegisterTypes(types map[AttributeType]TypeInfo) error {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := RegisterType(AttributeType(name), types[AttributeType(name)]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LookupType returns how typ is generated, arrays of a registered type
+// included (i.e. "UUID[]")
+func LookupType(typ AttributeType) (TypeInfo, bool) {
+	if info, ok := typeRegistry[typ]; ok {
+		return info, true
+	}
+
+	element := AttributeType(strings.TrimSuffix(string(typ), "[]"))
+	if element == typ {
+		return lookupParameterized(typ)
+	}
+
+	info, ok := LookupType(element)
+	if !ok {
+		return info, false
+	}
+
+	info.SQL += "[]"
+	info.MySQL = "JSON"
+	info.SQLite = "TEXT"
+	info.Proto = "repeated " + info.Proto
+	info.ProtoWrapper = ""
+	info.Go = "[]" + info.Go
+	info.GoNull, info.GoNullImport = "", ""
+	if info.TestValue != "" {
+		info.TestValue = fmt.Sprintf("%s{%s}", info.Go, info.TestValue)
+	}
+
+	return info, true
+}
+
+// lookupParameterized returns how a type with a length or precision is
+// generated (i.e. varchar(40))
+func lookupParameterized(typ AttributeType) (TypeInfo, bool) {
+	base, params, ok := splitParams(string(typ))
+	if !ok || parameterizedTypes[AttributeType(base)] == nil || !parameterizedTypes[AttributeType(base)].MatchString(params) {
+		return TypeInfo{}, false
+	}
+
+	info, ok := typeRegistry[AttributeType(base)]
+	if !ok {
+		return info, false
+	}
+	sql, _, _ := splitParams(info.SQL)
+	info.SQL = sql + "(" + params + ")"
+	if info.MySQL != "" {
+		mysql, _, _ := splitParams(info.MySQL)
+		info.MySQL = mysql + "(" + params + ")"
+	}
+
+	return info, true
+}
+
+// splitParams splits "numeric(12, 2)" into "numeric" and "12,2"
+func splitParams(typ string) (string, string, bool) {
+	open := strings.Index(typ, "(")
+	if open <= 0 || !strings.HasSuffix(typ, ")") {
+		return typ, "", false
+	}
+
+	params := strings.ReplaceAll(typ[open+1:len(typ)-1], " ", "")
+
+	return strings.TrimSpace(typ[:open]), params, true
+}
+
+// NormalizeType returns the AttributeType of a SQL type spelling (i.e. int4[]
+// is integer[], character varying(40) is varchar(40) and varchar of the string
+// length is string)
+func NormalizeType(typ string) AttributeType {
+	typ = strings.TrimSpace(typ)
+	if _, ok := typeRegistry[AttributeType(typ)]; ok {
+		return AttributeType(typ)
+	}
+
+	arrays := ""
+	for strings.HasSuffix(typ, "[]") {
+		typ = strings.TrimSpace(strings.TrimSuffix(typ, "[]"))
+		arrays += "[]"
+	}
+
+	base, params, ok := splitParams(typ)
+	if alias, found := typeAliases[strings.ToLower(base)]; found {
+		base = string(alias)
+	} else if _, found := typeRegistry[AttributeType(strings.ToLower(base))]; found {
+		base = strings.ToLower(base)
+	}
+
+	if !ok {
+		return AttributeType(base + arrays)
+	}
+
+	if base == "varchar" && params == stringLength() {
+		return AttributeType("string" + arrays)
+	}
+
+	return AttributeType(base + "(" + params + ")" + arrays)
+}
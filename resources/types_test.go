@@ -0,0 +1,257 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// withTypes restores the type registry once the test is done
+func withTypes(t *testing.T) {
+	t.Cleanup(ResetTypes)
+}
+
+func TestLookupType(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    AttributeType
+		want   TypeInfo
+		wantOk bool
+	}{
+		{
+			name:   "a built in type",
+			typ:    "timestamptz",
+			want:   typeRegistry["timestamptz"],
+			wantOk: true,
+		},
+		{
+			name: "an array of a built in type",
+			typ:  "UUID[]",
+			want: TypeInfo{
+				SQL:       "UUID[]",
+				MySQL:     "JSON",
+				SQLite:    "TEXT",
+				Proto:     "repeated shared.UUID",
+				Go:        "[]uuid.UUID",
+				GoImport:  uuidImport,
+				TestValue: "[]uuid.UUID{uuid.NewV4()}",
+			},
+			wantOk: true,
+		},
+		{
+			name: "a type with a length",
+			typ:  "varchar(40)",
+			want: TypeInfo{
+				SQL:          "varchar(40)",
+				MySQL:        "VARCHAR(40)",
+				SQLite:       "TEXT",
+				Proto:        "string",
+				ProtoWrapper: "google.protobuf.StringValue",
+				Go:           "string",
+				GoNull:       "sql.NullString",
+				GoNullImport: "database/sql",
+				TestValue:    `"varchar"`,
+			},
+			wantOk: true,
+		},
+		{
+			name: "an array of a type with a precision",
+			typ:  "numeric(12,2)[]",
+			want: TypeInfo{
+				SQL:       "numeric(12,2)[]",
+				MySQL:     "JSON",
+				SQLite:    "TEXT",
+				Proto:     "repeated string",
+				Go:        "[]string",
+				TestValue: `[]string{"1.50"}`,
+			},
+			wantOk: true,
+		},
+		{
+			name: "a type that takes no length",
+			typ:  "text(40)",
+		},
+		{
+			name: "an invalid precision",
+			typ:  "numeric(a,b)",
+		},
+		{
+			name: "an unknown type",
+			typ:  "citext",
+		},
+		{
+			name: "an array of an unknown type",
+			typ:  "citext[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("LookupType", t, func() {
+				got, ok := LookupType(tt.typ)
+				So(ok, ShouldEqual, tt.wantOk)
+				So(got, ShouldResemble, tt.want)
+			})
+		})
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want AttributeType
+	}{
+		{typ: "UUID", want: "UUID"},
+		{typ: "uuid[]", want: "UUID[]"},
+		{typ: "int4", want: "integer"},
+		{typ: "INT8[]", want: "bigint[]"},
+		{typ: "float8", want: "double precision"},
+		{typ: "bool", want: "boolean"},
+		{typ: "character varying(40)", want: "varchar(40)"},
+		{typ: "varchar(120)", want: "string"},
+		{typ: "decimal(12, 2)", want: "numeric(12,2)"},
+		{typ: "timestamp with time zone", want: "timestamptz"},
+		{typ: "timestamp without time zone", want: "timestamp"},
+		{typ: "citext", want: "citext"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			Convey("NormalizeType", t, func() {
+				So(NormalizeType(tt.typ), ShouldEqual, tt.want)
+			})
+		})
+	}
+}
+
+func TestAttributeType_conversions(t *testing.T) {
+	Convey("every built in type generates a SQL, proto and go type", t, func() {
+		for _, typ := range AttributeTypes {
+			So(typ.ToSQL(), ShouldNotBeEmpty)
+			So(typ.ToProto(), ShouldNotBeEmpty)
+			So(typ.ToGo(), ShouldNotBeEmpty)
+			So(typ.TestValue(), ShouldNotBeEmpty)
+		}
+	})
+
+	Convey("string columns become proto strings", t, func() {
+		So(Attribute{Name: "text", Type: "string"}.ToProto(), ShouldEqual, "string Text")
+		So(AttributeType("string").ToSQL(), ShouldEqual, "varchar(120)")
+	})
+
+	Convey("every output of a postgres table uses the registered types", t, func() {
+		resource := Resource{
+			Package: "main",
+			CreateTable: CreateTable{
+				TableName: "payment",
+				Attributes: Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "amount", Type: "numeric(12,2)"},
+					{Name: "code", Type: "varchar(8)"},
+					{Name: "client_ip", Type: "inet", Nullable: true},
+					{Name: "tags", Type: "text[]"},
+					{Name: "settled_at", Type: "timestamp"},
+				},
+			},
+			CrudOptions: []CrudOption{"create"},
+		}
+
+		migration := GenerateMigration(resource)[0].Output
+		So(migration, ShouldContainSubstring, "\tamount numeric(12,2) NOT NULL,\n\tcode varchar(8) NOT NULL,\n\tclient_ip inet,\n\ttags text[] NOT NULL,\n\tsettled_at timestamp NOT NULL,\n\tPRIMARY KEY (id)\n")
+
+		schema := GenerateSQL(resource)[2].Output
+		So(schema, ShouldContainSubstring, "    amount numeric(12,2) NOT NULL,\n")
+
+		proto := GenerateProto(resource)[0].Output
+		So(proto, ShouldContainSubstring, "  string Amount = 1; // precision 12, scale 2\n  string Code = 2; // max length 8\n  optional string ClientIp = 3;\n  repeated string Tags = 4;\n  google.protobuf.Timestamp SettledAt = 5;\n")
+
+		tests := GenerateTests(resource)[0].Output
+		So(tests, ShouldContainSubstring, "\t\"context\"\n\t\"testing\"\n\t\"time\"\n")
+		So(tests, ShouldContainSubstring, "\t\"github.com/sqlc-dev/pqtype\"\n")
+		So(tests, ShouldContainSubstring, "expectedClientIp := pqtype.Inet{Valid: true}")
+		So(tests, ShouldContainSubstring, "ClientIp: expectedClientIp,")
+		So(tests, ShouldContainSubstring, "expectedTags := []string{\"text\"}")
+	})
+
+	Convey("unknown types are used as is in SQL", t, func() {
+		So(AttributeType("citext").ToSQL(), ShouldEqual, "citext")
+		So(AttributeType("citext").ToProto(), ShouldBeEmpty)
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	withTypes(t)
+
+	money := TypeInfo{SQL: "numeric(12,2)", Proto: "string", Go: "string", TestValue: `"1.00"`}
+
+	Convey("RegisterType", t, func() {
+		Convey("adds a type every generator uses", func() {
+			So(RegisterType("money", money), ShouldBeNil)
+			So(AttributeTypes[len(AttributeTypes)-1], ShouldEqual, AttributeType("money"))
+			So(AttributeType("money").IsValid(), ShouldBeTrue)
+			So(AttributeType("money[]").ToSQL(), ShouldEqual, "numeric(12,2)[]")
+
+			resource := Resource{
+				CreateTable: CreateTable{
+					TableName:  "invoice",
+					Attributes: Attributes{{Name: "id", Type: "UUID"}, {Name: "total", Type: "money"}},
+				},
+			}
+			So(GenerateSQL(resource)[2].Output, ShouldContainSubstring, "total numeric(12,2) NOT NULL")
+		})
+
+		Convey("replaces a registered type without listing it twice", func() {
+			count := len(AttributeTypes)
+			So(RegisterType("string", TypeInfo{SQL: "varchar(255)", Proto: "string", Go: "string"}), ShouldBeNil)
+			So(AttributeTypes, ShouldHaveLength, count)
+			So(AttributeType("string").ToSQL(), ShouldEqual, "varchar(255)")
+		})
+
+		Convey("requires a name and the sql, proto and go types", func() {
+			So(RegisterType("", money), ShouldNotBeNil)
+			So(RegisterType("money[]", money), ShouldNotBeNil)
+			So(RegisterType("money", TypeInfo{Proto: "string", Go: "string"}), ShouldNotBeNil)
+			So(RegisterType("money", TypeInfo{SQL: "numeric", Go: "string"}), ShouldNotBeNil)
+			So(RegisterType("money", TypeInfo{SQL: "numeric", Proto: "string"}), ShouldNotBeNil)
+		})
+
+		Convey("is undone by ResetTypes", func() {
+			ResetTypes()
+			count := len(AttributeTypes)
+			So(RegisterType("money", money), ShouldBeNil)
+			So(RegisterType("string", TypeInfo{SQL: "varchar(255)", Proto: "string", Go: "string"}), ShouldBeNil)
+
+			ResetTypes()
+			So(AttributeTypes, ShouldHaveLength, count)
+			So(AttributeType("money").IsValid(), ShouldBeFalse)
+			So(AttributeType("string").ToSQL(), ShouldEqual, "varchar(120)")
+		})
+	})
+}
+
+func TestResource_imports(t *testing.T) {
+	resource := Resource{
+		CreateTable: CreateTable{
+			TableName: "event",
+			Attributes: Attributes{
+				{Name: "id", Type: "UUID"},
+				{Name: "payload", Type: "jsonb"},
+				{Name: "created_at", Type: "timestamptz"},
+				{Name: "updated_at", Type: "timestamptz"},
+			},
+		},
+		CrudOptions: []CrudOption{"index"},
+	}
+
+	Convey("ProtoImports lists the files of the crud message types once", t, func() {
+		So(resource.ProtoImports(), ShouldResemble, []string{
+			"google/protobuf/struct.proto",
+			"google/protobuf/timestamp.proto",
+		})
+		So(GenerateProto(resource)[0].Output, ShouldContainSubstring, "import \"google/protobuf/timestamp.proto\";\nmessage ListEvent {")
+	})
+
+	Convey("TestStdImports and TestImports split the go packages", t, func() {
+		So(resource.TestStdImports(), ShouldResemble, []string{"context", "encoding/json", "testing", "time"})
+		So(resource.TestImports(), ShouldResemble, []string{uuidImport})
+		So(resource.GoType("created_at"), ShouldEqual, "time.Time")
+	})
+}
@@ -0,0 +1,228 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedWords are the postgres keywords that cannot name a table, column or
+// index without quoting
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "authorization": true,
+	"binary": true, "both": true, "case": true, "cast": true, "check": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true, "constraint": true,
+	"create": true, "cross": true, "current_catalog": true, "current_date": true, "current_role": true,
+	"current_schema": true, "current_time": true, "current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true, "else": true,
+	"end": true, "except": true, "false": true, "fetch": true, "for": true,
+	"foreign": true, "freeze": true, "from": true, "full": true, "grant": true,
+	"group": true, "having": true, "ilike": true, "in": true, "initially": true,
+	"inner": true, "intersect": true, "into": true, "is": true, "isnull": true,
+	"join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true,
+	"or": true, "order": true, "outer": true, "overlaps": true, "placing": true,
+	"primary": true, "references": true, "returning": true, "right": true, "select": true,
+	"session_user": true, "similar": true, "some": true, "symmetric": true, "table": true,
+	"tablesample": true, "then": true, "to": true, "trailing": true, "true": true,
+	"union": true, "unique": true, "user": true, "using": true, "variadic": true,
+	"verbose": true, "when": true, "where": true, "window": true, "with": true,
+}
+
+// Diagnostic is a problem found in a resource, Field is the path of the
+// offending value (i.e. "attributes[2].type")
+type Diagnostic struct {
+	Field   string
+	Message string
+}
+
+func (d Diagnostic) Error() string {
+	if d.Field == "" {
+		return d.Message
+	}
+
+	return d.Field + ": " + d.Message
+}
+
+// Diagnostics are every problem found in a resource
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Err returns the diagnostics as an error, or nil when there are none
+func (d Diagnostics) Err() error {
+	if len(d) == 0 {
+		return nil
+	}
+
+	return d
+}
+
+func (d *Diagnostics) add(field string, format string, args ...interface{}) {
+	*d = append(*d, Diagnostic{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// IsValid returns whether the type is registered, arrays of a registered type
+// included (i.e. "UUID[]")
+func (a AttributeType) IsValid() bool {
+	_, ok := LookupType(a)
+	return ok
+}
+
+// IsValid returns whether goils generates a message and queries for the option
+func (c CrudOption) IsValid() bool {
+	return c.MessageName() != ""
+}
+
+// Validate checks the resource before any template runs and returns every
+// problem found (i.e. an index column that is not an attribute)
+func (r Resource) Validate() Diagnostics {
+	d := Diagnostics{}
+
+	if r.TableName == "" {
+		d.add("table_name", "is required")
+	} else {
+		checkName(&d, "table_name", r.TableName)
+	}
+
+	if len(r.Attributes) == 0 {
+		d.add("attributes", "at least one attribute is required")
+	}
+
+	attributes := map[string]bool{}
+	enums := map[AttributeType][]string{}
+	_, postgres := Database.(Postgres)
+	for i, attr := range r.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+
+		switch {
+		case attr.Name == "":
+			d.add(field+".name", "is required")
+		case attributes[attr.Name]:
+			d.add(field+".name", "duplicate attribute %q", attr.Name)
+		default:
+			checkName(&d, field+".name", attr.Name)
+		}
+		attributes[attr.Name] = true
+
+		switch {
+		case attr.IsEnum():
+			validateEnum(&d, field, attr)
+			if other, ok := enums[attr.Type]; ok && strings.Join(other, ",") != strings.Join(attr.Values, ",") {
+				d.add(field+".values", "enum %q is declared with other values", attr.Type)
+			}
+			enums[attr.Type] = attr.Values
+		case !attr.Type.IsValid():
+			d.add(field+".type", "unknown attribute type %q%s", attr.Type, typeHint(attr.Type))
+		default:
+			info, _ := LookupType(attr.Type)
+			if err := checkParams(info.SQL); err != nil {
+				d.add(field+".type", "%v", err)
+			}
+		}
+
+		switch {
+		case attr.Generated != "" && attr.Default != "":
+			d.add(field+".default", "a generated column cannot have a default")
+		case attr.Generated != "" && attr.Create:
+			d.add(field+".create", "a generated column cannot be created")
+		}
+
+		switch {
+		case attr.Identity == "":
+		case attr.Identity != IdentityAlways && attr.Identity != IdentityByDefault:
+			d.add(field+".identity", "must be %s or %s, not %q", IdentityAlways, IdentityByDefault, attr.Identity)
+		case attr.Type != "smallint" && attr.Type != "integer" && attr.Type != "bigint":
+			d.add(field+".identity", "an identity column must be a smallint, integer or bigint, not %s", attr.Type)
+		case attr.Generated != "" || attr.Default != "":
+			d.add(field+".identity", "an identity column cannot have a default or be generated")
+		case attr.Nullable:
+			d.add(field+".nullable", "an identity column cannot be nullable")
+		case !postgres && !sameColumns(r.Key(), []string{attr.Name}):
+			// AUTO_INCREMENT and sqlite's rowid only number the key
+			d.add(field+".identity", "a %s identity column must be the primary key", Database.Name())
+		case attr.Identity == IdentityAlways && attr.Create:
+			d.add(field+".create", "a GENERATED ALWAYS identity column cannot be created")
+		}
+	}
+
+	indexes := map[string]bool{}
+	for i, index := range r.Indexes {
+		field := fmt.Sprintf("indexes[%d]", i)
+
+		switch {
+		case index.Name == "":
+			d.add(field+".name", "is required")
+		case indexes[index.Name]:
+			d.add(field+".name", "duplicate index %q", index.Name)
+		default:
+			checkName(&d, field+".name", index.Name)
+		}
+		indexes[index.Name] = true
+
+		if len(index.Columns) == 0 {
+			d.add(field+".columns", "at least one column is required")
+		}
+		for j, column := range index.Columns {
+			if !attributes[column] {
+				d.add(fmt.Sprintf("%s.columns[%d]", field, j), "%q is not an attribute", column)
+			}
+		}
+	}
+
+	r.validatePrimaryKey(&d)
+	r.validateReferences(&d)
+	r.validateHasManyThrough(&d)
+	r.validateConstraints(&d, attributes, indexes)
+
+	for i, option := range r.CrudOptions {
+		switch {
+		case !option.IsValid():
+			d.add(fmt.Sprintf("crud_options[%d]", i), "unknown crud option %q (expected one of %s)", option, crudOptionList())
+		case option == "show" && len(r.Attributes) > 0 && len(r.Key()) == 0:
+			d.add(fmt.Sprintf("crud_options[%d]", i), "show needs a primary_key (or an id attribute)")
+		}
+	}
+
+	if r.ProtoNullable != "" && r.ProtoNullable != NullableOptional && r.ProtoNullable != NullableWrapper {
+		d.add("proto_nullable", "unknown option %q (expected one of %s)", r.ProtoNullable, strings.Join(ProtoNullableOptions, ", "))
+	}
+
+	return d
+}
+
+// checkName reports names that are SQL reserved words
+func checkName(d *Diagnostics, field string, name string) {
+	if reservedWords[strings.ToLower(name)] {
+		d.add(field, "%q is a reserved SQL word", name)
+	}
+}
+
+// typeHint suggests the supported spelling of typ, if any
+func typeHint(typ AttributeType) string {
+	if normalized := NormalizeType(string(typ)); normalized != typ && normalized.IsValid() {
+		return fmt.Sprintf(" (did you mean %q?)", normalized)
+	}
+
+	return ""
+}
+
+func crudOptionList() string {
+	options := make([]string, len(CrudOptions))
+	for i, option := range CrudOptions {
+		options[i] = string(option)
+	}
+
+	return strings.Join(options, ", ")
+}
@@ -0,0 +1,169 @@
+package resources
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource_Validate(t *testing.T) {
+	valid := func() Resource {
+		return Resource{
+			CreateTable: CreateTable{
+				TableName: "sms",
+				Attributes: Attributes{
+					{Name: "id", Type: "UUID"},
+					{Name: "locationid", Type: "UUID[]"},
+					{Name: "text", Type: "string", Nullable: true},
+				},
+				Indexes: Indexes{
+					{Name: "idx_sms_location_id", Type: "BTREE", Columns: []string{"locationid", "id"}},
+				},
+			},
+			CrudOptions: []CrudOption{"show", "index", "create"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *Resource)
+		want   Diagnostics
+	}{
+		{
+			name:   "a valid resource",
+			modify: func(r *Resource) {},
+			want:   Diagnostics{},
+		},
+		{
+			name:   "empty table name",
+			modify: func(r *Resource) { r.TableName = "" },
+			want:   Diagnostics{{Field: "table_name", Message: "is required"}},
+		},
+		{
+			name:   "no attributes",
+			modify: func(r *Resource) { r.Attributes = nil; r.Indexes = nil },
+			want:   Diagnostics{{Field: "attributes", Message: "at least one attribute is required"}},
+		},
+		{
+			name: "duplicate attribute and index names",
+			modify: func(r *Resource) {
+				r.Attributes = append(r.Attributes, Attribute{Name: "text", Type: "text"})
+				r.Indexes = append(r.Indexes, Index{Name: "idx_sms_location_id", Type: "BTREE", Columns: []string{"id"}})
+			},
+			want: Diagnostics{
+				{Field: "attributes[3].name", Message: `duplicate attribute "text"`},
+				{Field: "indexes[1].name", Message: `duplicate index "idx_sms_location_id"`},
+			},
+		},
+		{
+			name: "unknown attribute type",
+			modify: func(r *Resource) {
+				r.Attributes[0].Type = "uuid"
+				r.Attributes[1].Type = "varchar(n)"
+				r.Attributes[2].Type = "citext"
+			},
+			want: Diagnostics{
+				{Field: "attributes[0].type", Message: `unknown attribute type "uuid" (did you mean "UUID"?)`},
+				{Field: "attributes[1].type", Message: `unknown attribute type "varchar(n)"`},
+				{Field: "attributes[2].type", Message: `unknown attribute type "citext"`},
+			},
+		},
+		{
+			name:   "index column that is not an attribute",
+			modify: func(r *Resource) { r.Indexes[0].Columns = []string{"locationid", "location_id"} },
+			want:   Diagnostics{{Field: "indexes[0].columns[1]", Message: `"location_id" is not an attribute`}},
+		},
+		{
+			name:   "unknown crud option",
+			modify: func(r *Resource) { r.CrudOptions = append(r.CrudOptions, "destroy") },
+			want: Diagnostics{
+				{Field: "crud_options[3]", Message: `unknown crud option "destroy" (expected one of show, index, create)`},
+			},
+		},
+		{
+			name: "generated columns with a default or created",
+			modify: func(r *Resource) {
+				r.Attributes = append(r.Attributes,
+					Attribute{Name: "length", Type: "integer", Generated: "char_length(text)", Default: "0"},
+					Attribute{Name: "words", Type: "integer", Generated: "1", Create: true},
+				)
+			},
+			want: Diagnostics{
+				{Field: "attributes[3].default", Message: "a generated column cannot have a default"},
+				{Field: "attributes[4].create", Message: "a generated column cannot be created"},
+			},
+		},
+		{
+			name: "identity columns of other kinds, types, defaults, NULL or created",
+			modify: func(r *Resource) {
+				r.Attributes = append(r.Attributes,
+					Attribute{Name: "a", Type: "bigint", Identity: "SOMETIMES"},
+					Attribute{Name: "b", Type: "text", Identity: IdentityAlways},
+					Attribute{Name: "c", Type: "bigint", Identity: IdentityAlways, Default: "0"},
+					Attribute{Name: "d", Type: "bigint", Identity: IdentityAlways, Nullable: true},
+					Attribute{Name: "e", Type: "bigint", Identity: IdentityAlways, Create: true},
+					Attribute{Name: "f", Type: "bigint", Identity: IdentityByDefault, Create: true},
+				)
+			},
+			want: Diagnostics{
+				{Field: "attributes[3].identity", Message: `must be ALWAYS or BY DEFAULT, not "SOMETIMES"`},
+				{Field: "attributes[4].identity", Message: "an identity column must be a smallint, integer or bigint, not text"},
+				{Field: "attributes[5].identity", Message: "an identity column cannot have a default or be generated"},
+				{Field: "attributes[6].nullable", Message: "an identity column cannot be nullable"},
+				{Field: "attributes[7].create", Message: "a GENERATED ALWAYS identity column cannot be created"},
+			},
+		},
+		{
+			name:   "primary key columns that are not attributes, repeated or nullable",
+			modify: func(r *Resource) { r.PrimaryKey = []string{"id", "location_id", "text", "id"} },
+			want: Diagnostics{
+				{Field: "primary_key[1]", Message: `"location_id" is not an attribute`},
+				{Field: "primary_key[2]", Message: `"text" is nullable, primary key columns cannot be`},
+				{Field: "primary_key[3]", Message: `duplicate column "id"`},
+			},
+		},
+		{
+			name: "show without a primary key",
+			modify: func(r *Resource) {
+				r.Attributes[0].Name = "uuid"
+				r.Indexes = nil
+			},
+			want: Diagnostics{{Field: "crud_options[0]", Message: "show needs a primary_key (or an id attribute)"}},
+		},
+		{
+			name: "reserved words",
+			modify: func(r *Resource) {
+				r.TableName = "user"
+				r.Attributes[2].Name = "Order"
+				r.Indexes = nil
+			},
+			want: Diagnostics{
+				{Field: "table_name", Message: `"user" is a reserved SQL word`},
+				{Field: "attributes[2].name", Message: `"Order" is a reserved SQL word`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Convey("Validate", t, func() {
+				resource := valid()
+				tt.modify(&resource)
+
+				So(resource.Validate(), ShouldResemble, tt.want)
+			})
+		})
+	}
+}
+
+func TestGenerateTemplates_invalid(t *testing.T) {
+	Convey("GenerateTemplates returns the diagnostics without running templates", t, func() {
+		resource := Resource{CreateTable: CreateTable{TableName: "sms"}}
+
+		group := GenerateSQL(resource)
+		So(group, ShouldHaveLength, 3)
+		So(group.AnyErrors(), ShouldBeTrue)
+		So(group[0].FileOut, ShouldEqual, "queries.sql")
+		So(group[0].Output, ShouldBeEmpty)
+		So(group[0].Error.Error(), ShouldEqual, "attributes: at least one attribute is required")
+	})
+}